@@ -0,0 +1,451 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+	"sort"
+
+	"github.com/disintegration/imaging"
+	bitset "github.com/skip2/go-qrcode/bitset"
+	reedsolomon "github.com/skip2/go-qrcode/reedsolomon"
+)
+
+// NewWithPicture constructs a QRCode whose data modules form picture, as
+// closely as possible, while still decoding to content.
+//
+//	var q *qrcode.QRCode
+//	q, err := qrcode.NewWithPicture("https://example.org", qrcode.Low, logo, 10)
+//
+// The QR Code is at least version minVersion (use 0 for the smallest version
+// which fits content). Larger versions have more room for the picture, as do
+// lower recovery levels and shorter content.
+//
+// A reader stops reading data at the terminator following content, so all of
+// the data bits after it (normally the pad codewords) are free to choose. The
+// error correction codewords are a linear function of the data bits, so the
+// free bits are solved for, to set the most important modules of the picture
+// first: Those which are darkest or lightest. Error correction still works as
+// normal.
+//
+// The rest of the encoding is chosen to match the picture best, from:
+//
+//   - The version: The smallest which fits (at least minVersion), and up to
+//     three larger versions.
+//   - The segments content is encoded in: As usual, as a single byte mode
+//     segment, or split into two byte mode segments. Each sets different
+//     content codeword values, and leaves a different number of free bits.
+//   - The mask.
+//
+// The picture is cropped to fill the symbol. Its finder, alignment and timing
+// patterns, and format and version information, are drawn as usual.
+func NewWithPicture(content string, level RecoveryLevel, picture image.Image, minVersion int) (*QRCode, error) {
+	q, err := NewWithMinimumVersion(content, minVersion, level)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *QRCode
+	bestScore := -1.0
+
+	for v := q.VersionNumber; v < q.VersionNumber+numPictureVersions && v <= 40; v++ {
+		candidate := q
+		if v != q.VersionNumber {
+			if candidate, err = NewWithForcedVersion(content, v, level); err != nil {
+				return nil, err
+			}
+		}
+
+		if score := candidate.fitPicture(picture); score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+
+	return best, nil
+}
+
+// numPictureVersions is the number of versions NewWithPicture tries.
+const numPictureVersions = 4
+
+// numPictureSplits is the number of ways NewWithPicture splits content into
+// two byte mode segments.
+const numPictureSplits = 3
+
+// artTarget is the desired value of each symbol module (excluding the quiet
+// zone), and its importance.
+type artTarget struct {
+	dark   [][]bool
+	weight [][]int
+
+	// Sum of every module's weight.
+	total int
+}
+
+// newArtTarget samples picture as a size*size module target.
+func newArtTarget(picture image.Image, size int) *artTarget {
+	fitted := imaging.Fill(picture, size, size, imaging.Center, imaging.Lanczos)
+
+	t := &artTarget{
+		dark:   make([][]bool, size),
+		weight: make([][]int, size),
+	}
+
+	for y := 0; y < size; y++ {
+		t.dark[y] = make([]bool, size)
+		t.weight[y] = make([]int, size)
+
+		for x := 0; x < size; x++ {
+			gray := int(color.GrayModel.Convert(flattenColor(fitted.At(x, y))).(color.Gray).Y)
+
+			t.dark[y][x] = gray < 0x80
+			t.weight[y][x] = gray - 0x80
+			if gray < 0x80 {
+				t.weight[y][x] = 0x7f - gray
+			}
+			t.total += t.weight[y][x]
+		}
+	}
+
+	return t
+}
+
+// artBlock is an error correction block, and how its bits depend on the free
+// data bits.
+type artBlock struct {
+	// Number of data and error correction codewords.
+	numData, numEC int
+
+	// Position of each codeword (data, then error correction) in the final
+	// interleaved sequence.
+	sequence []int
+
+	// Each bit of the block is constant[i] XOR the parity of the free bits
+	// selected by row[i].
+	constant []bool
+	row      []gf2Row
+
+	// Index of the block's first free bit in the QR Code data, and the number
+	// of free bits.
+	firstFree, numFree int
+}
+
+// fitPicture chooses the segments, free data bits and mask of q to match
+// picture. It returns the proportion of picture matched by the whole symbol,
+// weighted by the importance of each module.
+func (q *QRCode) fitPicture(picture image.Image) float64 {
+	version := q.version
+	numDataBits := version.numDataBits()
+
+	m := newRegularSymbol(version, 0, nil, false)
+	positions := m.dataModulePositions(numDataBits + 8*version.numECCodewords())
+	target := newArtTarget(picture, m.size)
+
+	// The function patterns (and format information) match the same modules
+	// for every choice of data.
+	var functionScore [8]int
+	for mask := range functionScore {
+		f := newRegularSymbol(version, mask, nil, false).symbol
+
+		for y := 0; y < f.size; y++ {
+			for x := 0; x < f.size; x++ {
+				if f.functionModule.get(x, y) && f.get(x, y) == target.dark[y][x] {
+					functionScore[mask] += target.weight[y][x]
+				}
+			}
+		}
+	}
+
+	bestScore := -1
+	var best *bitset.Bitset
+
+	for _, content := range q.pictureSegmentations() {
+		if content.Len() > numDataBits {
+			continue
+		}
+
+		// The content and terminator are fixed. The remaining bits are free,
+		// and start as zero.
+		data := bitset.Clone(content)
+		data.AppendNumBools(version.numTerminatorBitsRequired(data.Len()), false)
+		numFixed := data.Len()
+		data.AppendNumBools(numDataBits-numFixed, false)
+
+		blocks := q.artBlocks(data, numFixed)
+
+		for mask := 0; mask < 8; mask++ {
+			var free []bool
+			score := functionScore[mask]
+
+			for _, b := range blocks {
+				solution := b.solve(mask, positions, target)
+				free = append(free, solution...)
+
+				score += b.score(solution, mask, positions, target)
+			}
+
+			if score > bestScore {
+				bestScore = score
+				best = data.Substr(0, numFixed)
+				best.AppendBools(free...)
+				q.mask = mask
+			}
+		}
+	}
+
+	q.data = best
+	q.maskFixed = true
+
+	if target.total == 0 {
+		return 0
+	}
+
+	return float64(bestScore) / float64(target.total)
+}
+
+// pictureSegmentations returns q's content encoded in each of the segment
+// choices NewWithPicture tries. The encodings may be too long for q's version.
+func (q *QRCode) pictureSegmentations() []*bitset.Bitset {
+	content := []byte(q.Content)
+
+	segmentations := [][]segment{
+		{{dataMode: dataModeByte, data: content}},
+	}
+	for i := 1; i <= numPictureSplits; i++ {
+		split := len(content) * i / (numPictureSplits + 1)
+		if split == 0 {
+			continue
+		}
+
+		segmentations = append(segmentations, []segment{
+			{dataMode: dataModeByte, data: content[:split]},
+			{dataMode: dataModeByte, data: content[split:]},
+		})
+	}
+
+	// The usual encoding comes first, so is kept in a tie.
+	encodings := []*bitset.Bitset{q.data}
+
+	for _, segments := range segmentations {
+		encoded := bitset.New()
+		for _, s := range segments {
+			if _, err := q.encoder.encodedLength(s.dataMode, len(s.data)); err != nil {
+				encoded = nil
+				break
+			}
+			q.encoder.encodeDataRaw(s.data, s.dataMode, encoded)
+		}
+
+		duplicate := encoded == nil
+		for _, e := range encodings {
+			if encoded != nil && e.Equals(encoded) {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			encodings = append(encodings, encoded)
+		}
+	}
+
+	return encodings
+}
+
+// artBlocks returns the error correction blocks of data, where all bits from
+// numFixed onwards are free.
+func (q *QRCode) artBlocks(data *bitset.Bitset, numFixed int) []*artBlock {
+	var blocks []*artBlock
+
+	start := 0
+	for _, vb := range q.version.block {
+		for j := 0; j < vb.numBlocks; j++ {
+			b := &artBlock{
+				numData:  vb.numDataCodewords,
+				numEC:    vb.numCodewords - vb.numDataCodewords,
+				sequence: make([]int, vb.numCodewords),
+			}
+
+			end := start + b.numData*8
+			b.firstFree = max(start, numFixed)
+			b.numFree = max(0, end-b.firstFree)
+
+			b.constant = reedsolomon.Encode(data.Substr(start, end), b.numEC).Bits()
+			b.row = make([]gf2Row, len(b.constant))
+			for i := range b.row {
+				b.row[i] = newGF2Row(b.numFree)
+			}
+
+			// Data bits are free bits themselves. Each error correction bit is
+			// a (linear) combination of the free bits.
+			for v := 0; v < b.numFree; v++ {
+				b.row[b.firstFree-start+v].set(v)
+
+				unit := make([]byte, b.numData)
+				i := b.firstFree - start + v
+				unit[i/8] = 0x80 >> uint(i%8)
+
+				ec := reedsolomon.EncodeBytes(unit, b.numEC)[b.numData:]
+				for i := 0; i < b.numEC*8; i++ {
+					if ec[i/8]&(0x80>>uint(i%8)) != 0 {
+						b.row[b.numData*8+i].set(v)
+					}
+				}
+			}
+
+			blocks = append(blocks, b)
+			start = end
+		}
+	}
+
+	for pos, c := range q.version.interleavedCodewords() {
+		blocks[c.block].sequence[c.index] = pos
+	}
+
+	return blocks
+}
+
+// module returns the position of bit i of the block in the symbol.
+func (b *artBlock) module(i int, positions []image.Point) image.Point {
+	return positions[b.sequence[i/8]*8+i%8]
+}
+
+// solve returns the free bits which make the block's modules match target
+// with mask applied, prioritising the modules with the highest weight.
+func (b *artBlock) solve(mask int, positions []image.Point, target *artTarget) []bool {
+	order := make([]int, len(b.row))
+	for i := range order {
+		order[i] = i
+	}
+
+	weight := func(i int) int {
+		p := b.module(i, positions)
+		return target.weight[p.Y][p.X]
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return weight(order[i]) > weight(order[j])
+	})
+
+	// Gaussian elimination over GF(2). Each module adds a constraint on the
+	// free bits, unless it's already determined by those added so far.
+	type pivot struct {
+		row gf2Row
+		rhs bool
+		col int
+	}
+	var pivots []pivot
+
+	for _, i := range order {
+		if len(pivots) == b.numFree {
+			break
+		}
+
+		p := b.module(i, positions)
+		want := target.dark[p.Y][p.X] != dataMask(mask, p.X, p.Y)
+
+		row := b.row[i].clone()
+		rhs := want != b.constant[i]
+
+		for _, pv := range pivots {
+			if row.get(pv.col) {
+				row.xor(pv.row)
+				rhs = rhs != pv.rhs
+			}
+		}
+
+		if col := row.first(); col >= 0 {
+			pivots = append(pivots, pivot{row, rhs, col})
+		}
+	}
+
+	// Back substitution, with the unconstrained bits left as zero.
+	free := newGF2Row(b.numFree)
+	for k := len(pivots) - 1; k >= 0; k-- {
+		pv := pivots[k]
+
+		if pv.rhs != pv.row.dot(free) {
+			free.set(pv.col)
+		}
+	}
+
+	solution := make([]bool, b.numFree)
+	for v := range solution {
+		solution[v] = free.get(v)
+	}
+
+	return solution
+}
+
+// score returns the total weight of the block's modules which match target
+// with the free bits set to solution.
+func (b *artBlock) score(solution []bool, mask int, positions []image.Point, target *artTarget) int {
+	free := newGF2Row(b.numFree)
+	for v, set := range solution {
+		if set {
+			free.set(v)
+		}
+	}
+
+	score := 0
+	for i := range b.row {
+		p := b.module(i, positions)
+		value := (b.constant[i] != b.row[i].dot(free)) != dataMask(mask, p.X, p.Y)
+
+		if value == target.dark[p.Y][p.X] {
+			score += target.weight[p.Y][p.X]
+		}
+	}
+
+	return score
+}
+
+// gf2Row is a vector over GF(2).
+type gf2Row []uint64
+
+func newGF2Row(n int) gf2Row {
+	return make(gf2Row, (n+63)/64)
+}
+
+func (r gf2Row) get(i int) bool {
+	return r[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (r gf2Row) set(i int) {
+	r[i/64] |= 1 << uint(i%64)
+}
+
+func (r gf2Row) clone() gf2Row {
+	return append(gf2Row(nil), r...)
+}
+
+func (r gf2Row) xor(other gf2Row) {
+	for i := range r {
+		r[i] ^= other[i]
+	}
+}
+
+// first returns the index of the first set element, or -1 if none are set.
+func (r gf2Row) first() int {
+	for i, w := range r {
+		if w != 0 {
+			for j := 0; j < 64; j++ {
+				if w&(1<<uint(j)) != 0 {
+					return i*64 + j
+				}
+			}
+		}
+	}
+
+	return -1
+}
+
+// dot returns the dot product of r and other.
+func (r gf2Row) dot(other gf2Row) bool {
+	var parity uint64
+	for i := range r {
+		parity ^= r[i] & other[i]
+	}
+
+	return bits.OnesCount64(parity)%2 == 1
+}
@@ -0,0 +1,289 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+	reedsolomon "github.com/skip2/go-qrcode/reedsolomon"
+)
+
+// ringPicture returns a black ring on a white background.
+func ringPicture() image.Image {
+	picture := image.NewGray(image.Rect(0, 0, 100, 100))
+
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			dx, dy := x-50, y-50
+			d := dx*dx + dy*dy
+
+			c := color.Gray{0xff}
+			if d > 20*20 && d < 40*40 {
+				c = color.Gray{0}
+			}
+			picture.SetGray(x, y, c)
+		}
+	}
+
+	return picture
+}
+
+// readData reads the data codewords back from q's symbol, checking the error
+// correction codewords of each block are correct.
+func readData(t *testing.T, q *QRCode) *bitset.Bitset {
+	q.encode()
+
+	m := newRegularSymbol(q.version, q.mask, nil, false)
+	numDataBits := q.version.numDataBits()
+	positions := m.dataModulePositions(numDataBits + 8*q.version.numECCodewords())
+
+	border := q.symbol.quietZoneSize
+	stream := bitset.New()
+	for _, p := range positions {
+		stream.AppendBools(q.symbol.module.get(p.X+border, p.Y+border) != dataMask(q.mask, p.X, p.Y))
+	}
+
+	// De-interleave the blocks.
+	var blocks [][]byte
+	var numData, numEC []int
+	for _, b := range q.version.block {
+		for j := 0; j < b.numBlocks; j++ {
+			blocks = append(blocks, nil)
+			numData = append(numData, b.numDataCodewords)
+			numEC = append(numEC, b.numCodewords-b.numDataCodewords)
+		}
+	}
+
+	r := bitset.NewReader(stream)
+	for _, data := range []bool{true, false} {
+		for i := 0; ; i++ {
+			placed := false
+
+			for j := range blocks {
+				n := numEC[j]
+				if data {
+					n = numData[j]
+				}
+
+				if i < n {
+					codeword, err := r.ReadBits(8)
+					if err != nil {
+						t.Fatal(err.Error())
+					}
+
+					blocks[j] = append(blocks[j], byte(codeword))
+					placed = true
+				}
+			}
+
+			if !placed {
+				break
+			}
+		}
+	}
+
+	result := bitset.New()
+	for j, b := range blocks {
+		data := b[:numData[j]]
+
+		if !bytes.Equal(reedsolomon.EncodeBytes(data, numEC[j]), b) {
+			t.Fatalf("block %d has incorrect error correction codewords", j)
+		}
+
+		result.AppendBytes(data)
+	}
+
+	return result
+}
+
+// matchingModules returns the proportion of q's data modules which match
+// picture.
+func matchingModules(q *QRCode, picture image.Image) float64 {
+	q.encode()
+
+	m := newRegularSymbol(q.version, q.mask, nil, false)
+	target := newArtTarget(picture, m.size)
+	border := q.symbol.quietZoneSize
+
+	positions := m.dataModulePositions(q.version.numDataBits() + 8*q.version.numECCodewords())
+
+	numMatching := 0
+	for _, p := range positions {
+		if q.symbol.module.get(p.X+border, p.Y+border) == target.dark[p.Y][p.X] {
+			numMatching++
+		}
+	}
+
+	return float64(numMatching) / float64(len(positions))
+}
+
+// decodeContent decodes the segments of data up to the terminator, with the
+// character count lengths of q's version.
+func decodeContent(t *testing.T, q *QRCode, data *bitset.Bitset) string {
+	const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+	r := bitset.NewReader(data)
+	read := func(n int) int {
+		v, err := r.ReadBits(n)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return int(v)
+	}
+
+	var content []byte
+	for r.Remaining() >= 4 {
+		var mode dataMode
+		switch read(4) {
+		case 0:
+			return string(content)
+		case 1:
+			mode = dataModeNumeric
+		case 2:
+			mode = dataModeAlphanumeric
+		case 4:
+			mode = dataModeByte
+		default:
+			t.Fatalf("unknown mode indicator in %s", data)
+		}
+
+		n := read(q.encoder.charCountBits(mode))
+		for n > 0 {
+			switch {
+			case mode == dataModeByte:
+				content = append(content, byte(read(8)))
+				n--
+			case mode == dataModeAlphanumeric && n > 1:
+				v := read(11)
+				content = append(content, alphanumeric[v/45], alphanumeric[v%45])
+				n -= 2
+			case mode == dataModeAlphanumeric:
+				content = append(content, alphanumeric[read(6)])
+				n--
+			case n >= 3:
+				content = append(content, fmt.Sprintf("%03d", read(10))...)
+				n -= 3
+			case n == 2:
+				content = append(content, fmt.Sprintf("%02d", read(7))...)
+				n -= 2
+			default:
+				content = append(content, fmt.Sprintf("%d", read(4))...)
+				n--
+			}
+		}
+	}
+
+	return string(content)
+}
+
+func TestNewWithPicture(t *testing.T) {
+	const content = "https://example.org"
+	picture := ringPicture()
+
+	for _, minVersion := range []int{0, 6} {
+		plain, err := NewWithMinimumVersion(content, minVersion, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		q, err := NewWithPicture(content, Low, picture, minVersion)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if q.VersionNumber < plain.VersionNumber || q.VersionNumber >= plain.VersionNumber+numPictureVersions {
+			t.Errorf("got version %d, expected %d to %d", q.VersionNumber,
+				plain.VersionNumber, plain.VersionNumber+numPictureVersions-1)
+		}
+
+		// The content still decodes.
+		if got := decodeContent(t, q, readData(t, q)); got != content {
+			t.Errorf("version %d decodes to %q, expected %q", q.VersionNumber, got, content)
+		}
+
+		// The picture's modules are matched far more than chance. The smallest
+		// version has little room for the picture.
+		plainMatch := matchingModules(plain, picture)
+		match := matchingModules(q, picture)
+
+		if match < plainMatch+0.1 || minVersion > 0 && match < 0.8 {
+			t.Errorf("version %d matches %.2f of modules, expected much more than %.2f",
+				q.VersionNumber, match, plainMatch)
+		}
+
+		// The version matching the picture best is chosen.
+		best, err := NewWithForcedVersion(content, q.VersionNumber, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		bestScore := best.fitPicture(picture)
+
+		for v := plain.VersionNumber; v < plain.VersionNumber+numPictureVersions; v++ {
+			other, err := NewWithForcedVersion(content, v, Low)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if score := other.fitPicture(picture); score > bestScore {
+				t.Errorf("version %d scores %.3f, better than the chosen version %d's %.3f",
+					v, score, q.VersionNumber, bestScore)
+			}
+		}
+	}
+}
+
+func TestPictureSegmentations(t *testing.T) {
+	const content = "HTTPS://EXAMPLE.ORG/12345678"
+
+	q, err := NewWithForcedVersion(content, 3, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	encodings := q.pictureSegmentations()
+
+	// The usual encoding, as one byte segment, and three splits.
+	if len(encodings) != 1+1+numPictureSplits {
+		t.Errorf("got %d encodings, expected %d", len(encodings), 2+numPictureSplits)
+	}
+	if !encodings[0].Equals(q.data) {
+		t.Error("usual encoding not first")
+	}
+
+	for i, e := range encodings {
+		data := bitset.Clone(e)
+		data.AppendNumBools(4, false)
+
+		if got := decodeContent(t, q, data); got != content {
+			t.Errorf("encoding %d decodes to %q, expected %q", i, got, content)
+		}
+	}
+}
+
+func TestNewWithPictureFullData(t *testing.T) {
+	// 41 digits fill version 1-L, leaving no free bits, nor room for other
+	// segments: Only the mask can be chosen.
+	const content = "01234567890123456789012345678901234567890"
+
+	q, err := NewWithForcedVersion(content, 1, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.fitPicture(ringPicture())
+
+	plain, err := New(content, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	plain.encode()
+
+	if !readData(t, q).Equals(plain.data) {
+		t.Error("full data changed, expected only the mask to be chosen")
+	}
+}
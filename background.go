@@ -0,0 +1,280 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"io/fs"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// BackgroundMode selects how BeautifyImage draws data modules over a
+// BackgroundImage.
+type BackgroundMode int
+
+const (
+	// BackgroundDots draws each data module as a smaller dot centred in the
+	// module, dark or light as the module. The photo shows around the dots.
+	BackgroundDots BackgroundMode = iota
+
+	// BackgroundAdjust lightens or darkens the photo under each data module,
+	// just enough for it to contrast with the modules of the other colour.
+	BackgroundAdjust
+)
+
+const (
+	// Default dot size for BackgroundDots, relative to the module size.
+	defaultBackgroundDotSize = 0.5
+
+	// Maximum difference in luminance between the photo under a module and the
+	// module's own colour for BackgroundAdjust, relative to the difference
+	// between the dark and light colours.
+	backgroundAdjustLimit = 0.3
+)
+
+// SetBackgroundImage sets the BackgroundImage and BackgroundMode.
+func (q *QRCode) SetBackgroundImage(img image.Image, mode BackgroundMode) {
+	q.BackgroundImage = &img
+	q.BackgroundMode = mode
+	q.backgroundImageCache = nil
+}
+
+// LoadAndSetBackgroundImage loads the image at path as the BackgroundImage.
+func (q *QRCode) LoadAndSetBackgroundImage(path string, mode BackgroundMode) error {
+	img, err := loadImage(path)
+	if err == nil {
+		q.SetBackgroundImage(img, mode)
+	}
+	return err
+}
+
+// ReadAndSetBackgroundImage decodes the image read from r as the
+// BackgroundImage.
+func (q *QRCode) ReadAndSetBackgroundImage(r io.Reader, mode BackgroundMode) error {
+	img, err := readImage(r)
+	if err == nil {
+		q.SetBackgroundImage(img, mode)
+	}
+	return err
+}
+
+// LoadAndSetBackgroundImageFS loads the image at path in fsys as the
+// BackgroundImage.
+func (q *QRCode) LoadAndSetBackgroundImageFS(fsys fs.FS, path string, mode BackgroundMode) error {
+	img, err := loadImageFS(fsys, path)
+	if err == nil {
+		q.SetBackgroundImage(img, mode)
+	}
+	return err
+}
+
+// drawBackgroundImage draws the BackgroundImage, cropped to fill the
+// size*size image img. The function patterns, quiet zone and any margin are
+// then drawn solid in the BackgroundColor, ready for the dark function pattern
+// modules to be drawn over. Readers rely on a clear quiet zone to find the
+// symbol.
+//
+// The modules are filled with their pixels in layout, which tile the symbol
+// without gaps, so no photo shows between them.
+func (q *QRCode) drawBackgroundImage(img draw.Image, size int, layout *pixelLayout) {
+	if q.backgroundImageCache == nil {
+		q.backgroundImageCache = make(map[int]image.Image)
+	}
+
+	photo, found := q.backgroundImageCache[size]
+	if !found {
+		filled := imaging.Fill(*q.BackgroundImage, size, size, imaging.Center, imaging.Lanczos)
+		photo = overlayImages(rectangleImage(size, size, q.BackgroundColor), filled, image.Point{})
+		q.backgroundImageCache[size] = photo
+	}
+
+	draw.Draw(img, img.Bounds(), photo, image.Point{}, draw.Src)
+
+	realSize := q.symbol.size
+
+	background := image.NewUniform(q.BackgroundColor)
+	functionPatterns := q.symbol.functionModule
+
+	// The quiet zone, a strip along each side.
+	if border := q.symbol.borderSize(); border > 0 {
+		last := realSize - 1
+		for _, strip := range [][4]int{
+			{0, 0, last, border - 1},
+			{0, realSize - border, last, last},
+			{0, border, border - 1, last - border},
+			{realSize - border, border, last, last - border},
+		} {
+			fillRect(img, layout.moduleRect(strip[0], strip[1]).Union(layout.moduleRect(strip[2], strip[3])),
+				background)
+		}
+	}
+
+	for y := 0; y < realSize; y++ {
+		for x := 0; x < realSize; x++ {
+			if functionPatterns.get(x, y) && q.symbol.inSymbol(x, y) {
+				fillRect(img, layout.moduleRect(x, y), background)
+			}
+		}
+	}
+
+	for p, m := range layout.module {
+		if m < 0 {
+			fillRect(img, image.Rect(p, 0, p+1, size), background)
+			fillRect(img, image.Rect(0, p, size, p+1), background)
+		}
+	}
+}
+
+// photoModules is what drawPhotoModule needs to draw the data modules of an
+// image over the background photo.
+type photoModules struct {
+	// Dots added by BackgroundDots, dark and light, drawn once all are added.
+	dark, light *maskLayer
+
+	// Sources of the dark and light modules.
+	darkSrc, lightSrc image.Image
+
+	// Largest difference in luminance left by BackgroundAdjust between the
+	// photo and the module.
+	limit float64
+}
+
+// newPhotoModules returns the photoModules for drawing data modules with
+// pixelSrc. Dark dots are added to dark.
+func (q *QRCode) newPhotoModules(dark *maskLayer, pixelSrc image.Image) *photoModules {
+	background := flattenColor(q.BackgroundColor)
+
+	return &photoModules{
+		dark:     dark,
+		light:    newMaskLayer(dark.bounds),
+		darkSrc:  pixelSrc,
+		lightSrc: image.NewUniform(q.BackgroundColor),
+		limit: backgroundAdjustLimit * math.Abs(luminance(background)-
+			luminance(compositeColor(q.PixelColor, background))),
+	}
+}
+
+// drawPhotoModule draws the data module occupying r over the background
+// photo. set is true for dark modules.
+//
+// With BackgroundDots the module's dot is added to m's dark or light layer, to
+// be drawn later. With BackgroundAdjust the photo under the module is adjusted
+// in place.
+func (q *QRCode) drawPhotoModule(img *image.RGBA, m *photoModules, r image.Rectangle, set bool) {
+	if q.BackgroundMode == BackgroundAdjust {
+		target := m.lightSrc
+		if set {
+			target = m.darkSrc
+		}
+
+		adjustPhoto(img, r, target, m.limit)
+		return
+	}
+
+	dotSize := q.BackgroundDotSize
+	if dotSize <= 0 || dotSize > 1 {
+		dotSize = defaultBackgroundDotSize
+	}
+
+	shape := q.ModuleShape
+	if shape == nil {
+		shape = SquareShape
+	}
+
+	l := m.light
+	if set {
+		l = m.dark
+	}
+
+	w := float64(r.Dx())
+	offset := (w - w*dotSize) / 2
+
+	l.addModule(shape, float64(r.Min.X)+offset, float64(r.Min.Y)+offset, w*dotSize, 0)
+}
+
+// adjustPhoto blends each pixel of img within r towards the colour of target
+// at that pixel, until the two differ in luminance by no more than limit.
+// Pixels already close enough are unchanged.
+func adjustPhoto(img *image.RGBA, r image.Rectangle, target image.Image, limit float64) {
+	r = r.Intersect(img.Bounds())
+
+	uniform, isUniform := target.(*image.Uniform)
+	var tr, tg, tb, ta uint32
+	if isUniform {
+		tr, tg, tb, ta = uniform.C.RGBA()
+	}
+
+	// The last pixel adjusted, and the result. Photos have runs of pixels of
+	// the same colour, adjusted the same towards a colour.
+	var last, lastAdjusted [4]uint8
+	haveLast := false
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4 : i+4]
+
+			if haveLast && p[0] == last[0] && p[1] == last[1] && p[2] == last[2] && p[3] == last[3] {
+				copy(p, lastAdjusted[:])
+				continue
+			}
+			if isUniform {
+				copy(last[:], p)
+				haveLast = true
+			}
+
+			pr, pg, pb, pa := uint32(p[0])*0x101, uint32(p[1])*0x101, uint32(p[2])*0x101, uint32(p[3])*0x101
+
+			if !isUniform {
+				tr, tg, tb, ta = sourceColor(target, x, y)
+			}
+			c := compositeRGBA64(tr, tg, tb, ta, pr, pg, pb)
+
+			diff := math.Abs(rgbLuminance(pr, pg, pb) - rgbLuminance(uint32(c.R), uint32(c.G), uint32(c.B)))
+			if diff <= limit {
+				copy(lastAdjusted[:], p)
+				continue
+			}
+
+			// Luminance is linear in the colour channels, so blending by t
+			// reduces the difference in luminance by the same proportion.
+			t := 1 - limit/diff
+
+			p[0] = blendChannel(pr, uint32(c.R), t)
+			p[1] = blendChannel(pg, uint32(c.G), t)
+			p[2] = blendChannel(pb, uint32(c.B), t)
+			if pa != 0xffff {
+				// Opaque pixels stay opaque.
+				p[3] = blendChannel(pa, uint32(c.A), t)
+			}
+			copy(lastAdjusted[:], p)
+		}
+	}
+}
+
+// blendChannel returns the 16-bit colour channel a blended by t towards b,
+// rounded, as an 8-bit channel.
+func blendChannel(a, b uint32, t float64) uint8 {
+	// The blend is never negative, so adding 0.5 and truncating rounds it to
+	// the nearest integer, faster than math.Round.
+	return uint8(uint16(float64(a)+t*(float64(b)-float64(a))+0.5) >> 8)
+}
+
+// luminance returns the (gamma encoded) luminance of c, from 0 to 1.
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+
+	return rgbLuminance(r, g, b)
+}
+
+// rgbLuminance returns the (gamma encoded) luminance of the 16-bit colour
+// channels r, g and b, from 0 to 1.
+func rgbLuminance(r, g, b uint32) float64 {
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+}
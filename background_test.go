@@ -0,0 +1,230 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testPhoto returns a mid gray image with a red stripe.
+func testPhoto() image.Image {
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 48))
+
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.RGBA{0x80, 0x80, 0x80, 0xff}
+			if y >= 20 && y < 28 {
+				c = color.RGBA{0xc0, 0x20, 0x20, 0xff}
+			}
+
+			photo.SetRGBA(x, y, c)
+		}
+	}
+
+	return photo
+}
+
+// findDataModules returns the position of a dark and a light data module.
+func findDataModules(q *QRCode) (dark, light image.Point) {
+	bitmap := q.symbol.bitmap()
+	functionPatterns := q.symbol.functionPatternBitmap()
+
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			if functionPatterns[y][x] || !q.symbol.inSymbol(x, y) {
+				continue
+			}
+
+			if bitmap[y][x] {
+				dark = image.Point{x, y}
+			} else {
+				light = image.Point{x, y}
+			}
+		}
+	}
+
+	return dark, light
+}
+
+func TestBackgroundImageDots(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	photo := testPhoto()
+	q.BackgroundImage = &photo
+
+	black := color.RGBA{0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	// 33 modules at 10px.
+	img := q.BeautifyImage(-10)
+	at := func(x, y int) color.RGBA {
+		return color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+	}
+
+	// The quiet zone is solid, hiding the photo.
+	if c := at(5, 5); c != white {
+		t.Errorf("quiet zone got %v, expected %v", c, white)
+	}
+
+	// The finder pattern, including its light ring and separator, is solid.
+	for _, p := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{41, 41, black},
+		{55, 55, white},
+		{75, 75, black},
+		{115, 45, white},
+	} {
+		if c := at(p.x, p.y); c != p.expected {
+			t.Errorf("finder pattern pixel (%d,%d) got %v, expected %v", p.x, p.y, c, p.expected)
+		}
+	}
+
+	// Data modules are dots half the module size, with the photo around them.
+	dark, light := findDataModules(q)
+	for _, m := range []struct {
+		p        image.Point
+		expected color.RGBA
+	}{
+		{dark, black},
+		{light, white},
+	} {
+		x, y := m.p.X*10, m.p.Y*10
+
+		if c := at(x+5, y+5); c != m.expected {
+			t.Errorf("module %v centre got %v, expected %v", m.p, c, m.expected)
+		}
+		if c := at(x+1, y+1); c == m.expected {
+			t.Errorf("module %v corner got %v, expected the photo", m.p, c)
+		}
+	}
+}
+
+func TestBackgroundImageUnevenSize(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	photo := testPhoto()
+	q.BackgroundImage = &photo
+
+	// 33 modules in 301px: Modules are drawn 9px wide, 1px apart.
+	const size = 301
+	img := q.BeautifyImage(size)
+
+	black := color.RGBA{0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	// Every pixel of the function patterns is black or white, with none of
+	// the photo showing between modules.
+	layout := newPixelLayout(size, q.symbol.size, ScaleNearest)
+	functionPatterns := q.symbol.functionPatternBitmap()
+
+	for y := range functionPatterns {
+		for x := range functionPatterns[y] {
+			if !functionPatterns[y][x] {
+				continue
+			}
+
+			r := layout.moduleRect(x, y)
+			for py := r.Min.Y; py < r.Max.Y; py++ {
+				for px := r.Min.X; px < r.Max.X; px++ {
+					c := color.RGBAModel.Convert(img.At(px, py)).(color.RGBA)
+					if c != black && c != white {
+						t.Fatalf("module (%d,%d) pixel (%d,%d) got %v, expected solid", x, y, px, py, c)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBackgroundImageQuietZone(t *testing.T) {
+	for _, mode := range []BackgroundMode{BackgroundDots, BackgroundAdjust} {
+		q, err := New("https://example.org", Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		background := color.RGBA{0xff, 0xf8, 0xe0, 0xff}
+		q.BackgroundColor = background
+		q.SetBackgroundImage(testPhoto(), mode)
+
+		// 33 modules at 10px.
+		const size = 330
+		img := q.BeautifyImage(size)
+
+		// Every pixel of the quiet zone is the background colour, with none
+		// of the photo showing.
+		layout := newPixelLayout(size, q.symbol.size, ScaleNearest)
+
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if q.symbol.inSymbol(layout.module[x], layout.module[y]) {
+					continue
+				}
+
+				if c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); c != background {
+					t.Fatalf("mode %d quiet zone pixel (%d,%d) got %v, expected %v", mode, x, y, c, background)
+				}
+			}
+		}
+	}
+}
+
+func TestBackgroundImageAdjust(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	photo := testPhoto()
+	q.BackgroundImage = &photo
+	q.BackgroundMode = BackgroundAdjust
+
+	img := q.BeautifyImage(-10)
+
+	bitmap := q.symbol.bitmap()
+	functionPatterns := q.symbol.functionPatternBitmap()
+
+	// Every pixel of every data module is within 30% of its module's colour.
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			if functionPatterns[y][x] || !q.symbol.inSymbol(x, y) {
+				continue
+			}
+
+			for j := 0; j < 10; j++ {
+				for i := 0; i < 10; i++ {
+					l := luminance(img.At(x*10+i, y*10+j))
+
+					if bitmap[y][x] && l > 0.301 || !bitmap[y][x] && l < 0.699 {
+						t.Fatalf("module (%d,%d) dark=%t pixel luminance %.3f", x, y, bitmap[y][x], l)
+					}
+				}
+			}
+		}
+	}
+
+	// The photo's colour is kept, not replaced with gray.
+	var numRed int
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if r, g, _, _ := img.At(x, y).RGBA(); r > g+0x2000 {
+				numRed++
+			}
+		}
+	}
+
+	if numRed == 0 {
+		t.Error("no red pixels remaining, expected the photo's red stripe to be adjusted")
+	}
+}
@@ -0,0 +1,169 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import "math/bits"
+
+// bitMatrix is a square matrix of bits, packed 64 to a word.
+//
+// Each row is stride words long, and bit x of a row is stored in bit x%64 of
+// word x/64. The unused bits at the end of each row are always zero, so rows
+// can be combined and counted a word at a time.
+type bitMatrix struct {
+	words  []uint64
+	stride int
+	size   int
+}
+
+// newBitMatrix returns an empty matrix of size*size bits.
+func newBitMatrix(size int) bitMatrix {
+	stride := (size + 63) / 64
+
+	return bitMatrix{
+		words:  make([]uint64, stride*size),
+		stride: stride,
+		size:   size,
+	}
+}
+
+// clone returns a copy of b.
+func (b bitMatrix) clone() bitMatrix {
+	c := b
+	c.words = append([]uint64(nil), b.words...)
+
+	return c
+}
+
+// row returns the words of row y.
+func (b bitMatrix) row(y int) []uint64 {
+	return b.words[y*b.stride : (y+1)*b.stride]
+}
+
+// get returns the bit at (x, y).
+func (b bitMatrix) get(x int, y int) bool {
+	return b.words[y*b.stride+x/64]&(1<<uint(x%64)) != 0
+}
+
+// set sets the bit at (x, y) to v.
+func (b bitMatrix) set(x int, y int, v bool) {
+	i := y*b.stride + x/64
+	bit := uint64(1) << uint(x%64)
+
+	// Written without a branch on v, which is unpredictable for data.
+	var value uint64
+	if v {
+		value = bit
+	}
+
+	b.words[i] = b.words[i]&^bit | value
+}
+
+// setBits sets the n bits of row y starting at x to the low n bits of v, with
+// bit 0 of v at x. n is at most 64.
+func (b bitMatrix) setBits(x int, y int, n int, v uint64) {
+	mask := uint64(1)<<uint(n) - 1
+	v &= mask
+
+	r := b.row(y)
+	i, shift := x/64, uint(x%64)
+
+	r[i] = r[i]&^(mask<<shift) | v<<shift
+	if shift != 0 && int(shift)+n > 64 {
+		r[i+1] = r[i+1]&^(mask>>(64-shift)) | v>>(64-shift)
+	}
+}
+
+// xor inverts each bit set in other, which must be the same size.
+func (b bitMatrix) xor(other bitMatrix) {
+	for i, w := range other.words {
+		b.words[i] ^= w
+	}
+}
+
+// count returns the number of bits set.
+func (b bitMatrix) count() int {
+	n := 0
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
+	}
+
+	return n
+}
+
+// transpose returns a new matrix with the bit at (x, y) set if the bit at
+// (y, x) of b is set.
+//
+// The matrix is transposed in blocks of 64x64 bits.
+func (b bitMatrix) transpose() bitMatrix {
+	t := newBitMatrix(b.size)
+
+	var block [64]uint64
+
+	for i := 0; i < b.stride; i++ {
+		for j := 0; j < b.stride; j++ {
+			// Block (j, i): Word j of rows i*64 onwards.
+			for k := range block {
+				block[k] = 0
+				if y := i*64 + k; y < b.size {
+					block[k] = b.words[y*b.stride+j]
+				}
+			}
+
+			transpose64(&block)
+
+			// Block (i, j) of the transposed matrix.
+			for k := range block {
+				if y := j*64 + k; y < b.size {
+					t.words[y*t.stride+i] = block[k]
+				}
+			}
+		}
+	}
+
+	return t
+}
+
+// transpose64 transposes a 64x64 bit matrix, by swapping ever smaller blocks
+// of it.
+func transpose64(a *[64]uint64) {
+	mask := uint64(0x00000000ffffffff)
+
+	for j := 32; j != 0; j >>= 1 {
+		for k := 0; k < 64; k = (k + j + 1) &^ j {
+			t := (a[k]>>uint(j) ^ a[k+j]) & mask
+			a[k] ^= t << uint(j)
+			a[k+j] ^= t
+		}
+
+		mask ^= mask << uint(j/2)
+	}
+}
+
+// bools returns the matrix as a newly allocated [y][x] array.
+func (b bitMatrix) bools() [][]bool {
+	values := make([]bool, b.size*b.size)
+	result := make([][]bool, b.size)
+
+	for y := range result {
+		result[y] = values[y*b.size : (y+1)*b.size : (y+1)*b.size]
+
+		for x := range result[y] {
+			result[y][x] = b.get(x, y)
+		}
+	}
+
+	return result
+}
+
+// packBools returns up to 64 values as bits, with values[0] as bit 0.
+func packBools(values []bool) uint64 {
+	var v uint64
+	for i, value := range values {
+		if value {
+			v |= 1 << uint(i)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,75 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitMatrix(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{1, 21, 64, 65, 185} {
+		b := newBitMatrix(size)
+		expected := make([][]bool, size)
+
+		for y := range expected {
+			expected[y] = make([]bool, size)
+			for x := range expected[y] {
+				v := r.Intn(2) == 1
+				expected[y][x] = v
+				b.set(x, y, v)
+			}
+		}
+
+		// Patterns set across word boundaries.
+		for i := 0; i < 100; i++ {
+			n := 1 + r.Intn(8)
+			if n > size {
+				n = size
+			}
+			x, y := r.Intn(size-n+1), r.Intn(size)
+
+			pattern := make([]bool, n)
+			for j := range pattern {
+				pattern[j] = r.Intn(2) == 1
+			}
+
+			b.setBits(x, y, n, packBools(pattern))
+			copy(expected[y][x:], pattern)
+		}
+
+		count := 0
+		for y := range expected {
+			for x, v := range expected[y] {
+				if b.get(x, y) != v {
+					t.Fatalf("size %d (%d,%d) got %t, expected %t", size, x, y, !v, v)
+				}
+				if v {
+					count++
+				}
+			}
+		}
+
+		if b.count() != count {
+			t.Errorf("size %d count got %d, expected %d", size, b.count(), count)
+		}
+
+		transposed := b.transpose()
+		bools := b.bools()
+		for y := range expected {
+			for x, v := range expected[y] {
+				if transposed.get(y, x) != v || bools[y][x] != v {
+					t.Fatalf("size %d (%d,%d) transposed or bools differ", size, x, y)
+				}
+			}
+		}
+
+		b.xor(b)
+		if b.count() != 0 {
+			t.Errorf("size %d got %d bits set after xor with itself", size, b.count())
+		}
+	}
+}
@@ -20,8 +20,9 @@
 package bitset
 
 import (
-	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
 )
 
@@ -35,13 +36,15 @@ type Bitset struct {
 	// The number of bits stored.
 	numBits int
 
-	// Storage for individual bits.
-	bits []byte
+	// Storage for individual bits, 64 to a word. Bit i is stored in word i/64,
+	// most significant bit first, so the bytes of each word are in order. The
+	// bits after numBits are always zero.
+	words []uint64
 }
 
 // New returns an initialised Bitset with optional initial bits v.
 func New(v ...bool) *Bitset {
-	b := &Bitset{numBits: 0, bits: make([]byte, 0)}
+	b := &Bitset{numBits: 0, words: make([]uint64, 0)}
 	b.AppendBools(v...)
 
 	return b
@@ -49,7 +52,7 @@ func New(v ...bool) *Bitset {
 
 // Clone returns a copy.
 func Clone(from *Bitset) *Bitset {
-	return &Bitset{numBits: from.numBits, bits: from.bits[:]}
+	return &Bitset{numBits: from.numBits, words: append([]uint64(nil), from.words...)}
 }
 
 // Substr returns a substring, consisting of the bits from indexes start to end.
@@ -61,11 +64,28 @@ func (b *Bitset) Substr(start int, end int) *Bitset {
 	result := New()
 	result.ensureCapacity(end - start)
 
-	for i := start; i < end; i++ {
-		if b.At(i) {
-			result.bits[result.numBits/8] |= 0x80 >> uint(result.numBits%8)
-		}
-		result.numBits++
+	for i := start; i < end; i += 64 {
+		n := min(64, end-i)
+		result.appendBits(b.bitsAt(i, n), n)
+	}
+
+	return result
+}
+
+// SubstrBytes returns the bits from indexes start to end as bytes. A final
+// partial byte is padded with zero bits, as in Bytes().
+func (b *Bitset) SubstrBytes(start int, end int) []byte {
+	if start > end || end > b.numBits {
+		log.Panicf("Out of range start=%d end=%d numBits=%d", start, end, b.numBits)
+	}
+
+	result := make([]byte, (end-start+7)/8)
+
+	i := start
+	for j := range result {
+		n := min(8, end-i)
+		result[j] = byte(b.bitsAt(i, n) << uint(8-n))
+		i += n
 	}
 
 	return result
@@ -78,7 +98,7 @@ func (b *Bitset) Substr(start int, end int) *Bitset {
 //
 // The function panics if the input string contains other characters.
 func NewFromBase2String(b2string string) *Bitset {
-	b := &Bitset{numBits: 0, bits: make([]byte, 0)}
+	b := &Bitset{numBits: 0, words: make([]uint64, 0)}
 
 	for _, c := range b2string {
 		switch c {
@@ -96,44 +116,75 @@ func NewFromBase2String(b2string string) *Bitset {
 }
 
 // AppendBytes appends a list of whole bytes.
+//
+// The bytes are appended 8 at a time, as a word.
 func (b *Bitset) AppendBytes(data []byte) {
+	b.ensureCapacity(8 * len(data))
+
+	for ; len(data) >= 8; data = data[8:] {
+		b.appendBits(binary.BigEndian.Uint64(data), 64)
+	}
+
 	for _, d := range data {
-		b.AppendByte(d, 8)
+		b.appendBits(uint64(d), 8)
 	}
 }
 
 // AppendByte appends the numBits least significant bits from value.
 func (b *Bitset) AppendByte(value byte, numBits int) {
-	b.ensureCapacity(numBits)
-
 	if numBits > 8 {
 		log.Panicf("numBits %d out of range 0-8", numBits)
 	}
 
-	for i := numBits - 1; i >= 0; i-- {
-		if value&(1<<uint(i)) != 0 {
-			b.bits[b.numBits/8] |= 0x80 >> uint(b.numBits%8)
-		}
-
-		b.numBits++
-	}
+	b.ensureCapacity(numBits)
+	b.appendBits(uint64(value), numBits)
 }
 
 // AppendUint32 appends the numBits least significant bits from value.
 func (b *Bitset) AppendUint32(value uint32, numBits int) {
-	b.ensureCapacity(numBits)
-
 	if numBits > 32 {
 		log.Panicf("numBits %d out of range 0-32", numBits)
 	}
 
-	for i := numBits - 1; i >= 0; i-- {
-		if value&(1<<uint(i)) != 0 {
-			b.bits[b.numBits/8] |= 0x80 >> uint(b.numBits%8)
-		}
+	b.ensureCapacity(numBits)
+	b.appendBits(uint64(value), numBits)
+}
 
-		b.numBits++
+// appendBits appends the numBits (at most 64) least significant bits from
+// value. The capacity must already be ensured.
+func (b *Bitset) appendBits(value uint64, numBits int) {
+	if numBits == 0 {
+		return
 	}
+
+	// value's bits are moved to the top of the word, then split across the
+	// last word and the next.
+	value <<= uint(64 - numBits)
+	i, offset := b.numBits/64, uint(b.numBits%64)
+
+	b.words[i] |= value >> offset
+	if offset != 0 && int(offset)+numBits > 64 {
+		b.words[i+1] = value << (64 - offset)
+	}
+
+	b.numBits += numBits
+}
+
+// bitsAt returns the numBits (at most 64) bits starting at index, as the
+// least significant bits of the result.
+func (b *Bitset) bitsAt(index int, numBits int) uint64 {
+	if numBits == 0 {
+		return 0
+	}
+
+	i, offset := index/64, uint(index%64)
+
+	value := b.words[i] << offset
+	if offset != 0 && int(offset)+numBits > 64 {
+		value |= b.words[i+1] >> (64 - offset)
+	}
+
+	return value >> uint(64-numBits)
 }
 
 // ensureCapacity ensures the Bitset can store an additional |numBits|.
@@ -143,16 +194,13 @@ func (b *Bitset) AppendUint32(value uint32, numBits int) {
 func (b *Bitset) ensureCapacity(numBits int) {
 	numBits += b.numBits
 
-	newNumBytes := numBits / 8
-	if numBits%8 != 0 {
-		newNumBytes++
-	}
+	newNumWords := (numBits + 63) / 64
 
-	if len(b.bits) >= newNumBytes {
+	if len(b.words) >= newNumWords {
 		return
 	}
 
-	b.bits = append(b.bits, make([]byte, newNumBytes+2*len(b.bits))...)
+	b.words = append(b.words, make([]uint64, newNumWords+len(b.words))...)
 }
 
 // Append bits copied from |other|.
@@ -161,11 +209,9 @@ func (b *Bitset) ensureCapacity(numBits int) {
 func (b *Bitset) Append(other *Bitset) {
 	b.ensureCapacity(other.numBits)
 
-	for i := 0; i < other.numBits; i++ {
-		if other.At(i) {
-			b.bits[b.numBits/8] |= 0x80 >> uint(b.numBits%8)
-		}
-		b.numBits++
+	for i := 0; i < other.numBits; i += 64 {
+		n := min(64, other.numBits-i)
+		b.appendBits(other.words[i/64]>>uint(64-n), n)
 	}
 }
 
@@ -175,7 +221,7 @@ func (b *Bitset) AppendBools(bits ...bool) {
 
 	for _, v := range bits {
 		if v {
-			b.bits[b.numBits/8] |= 0x80 >> uint(b.numBits%8)
+			b.words[b.numBits/64] |= 1 << uint(63-b.numBits%64)
 		}
 		b.numBits++
 	}
@@ -183,8 +229,16 @@ func (b *Bitset) AppendBools(bits ...bool) {
 
 // AppendNumBools appends num bits of value value.
 func (b *Bitset) AppendNumBools(num int, value bool) {
-	for i := 0; i < num; i++ {
-		b.AppendBools(value)
+	b.ensureCapacity(num)
+
+	var v uint64
+	if value {
+		v = ^uint64(0)
+	}
+
+	for ; num > 0; num -= 64 {
+		n := min(64, num)
+		b.appendBits(v, n)
 	}
 }
 
@@ -196,7 +250,7 @@ func (b *Bitset) String() string {
 			bitString += " "
 		}
 
-		if (b.bits[i/8] & (0x80 >> byte(i%8))) != 0 {
+		if b.At(i) {
 			bitString += "1"
 		} else {
 			bitString += "0"
@@ -217,19 +271,33 @@ func (b *Bitset) Bits() []bool {
 
 	var i int
 	for i = 0; i < b.numBits; i++ {
-		result[i] = (b.bits[i/8] & (0x80 >> byte(i%8))) != 0
+		result[i] = b.words[i/64]&(1<<uint(63-i%64)) != 0
 	}
 
 	return result
 }
 
+// Bytes returns the contents of the Bitset as bytes, with the first bit as the
+// most significant bit of the first byte. A final partial byte is padded with
+// zero bits.
+func (b *Bitset) Bytes() []byte {
+	numWords := (b.numBits + 63) / 64
+
+	result := make([]byte, 8*numWords)
+	for i := 0; i < numWords; i++ {
+		binary.BigEndian.PutUint64(result[8*i:], b.words[i])
+	}
+
+	return result[:(b.numBits+7)/8]
+}
+
 // At returns the value of the bit at |index|.
 func (b *Bitset) At(index int) bool {
 	if index >= b.numBits {
 		log.Panicf("Index %d out of range", index)
 	}
 
-	return (b.bits[index/8] & (0x80 >> byte(index%8))) != 0
+	return b.words[index/64]&(1<<uint(63-index%64)) != 0
 }
 
 // Equals returns true if the Bitset equals other.
@@ -238,15 +306,9 @@ func (b *Bitset) Equals(other *Bitset) bool {
 		return false
 	}
 
-	if !bytes.Equal(b.bits[0:b.numBits/8], other.bits[0:b.numBits/8]) {
-		return false
-	}
-
-	for i := 8 * (b.numBits / 8); i < b.numBits; i++ {
-		a := (b.bits[i/8] & (0x80 >> byte(i%8)))
-		b := (other.bits[i/8] & (0x80 >> byte(i%8)))
-
-		if a != b {
+	// The bits after numBits are zero, so whole words can be compared.
+	for i := 0; i < (b.numBits+63)/64; i++ {
+		if b.words[i] != other.words[i] {
 			return false
 		}
 	}
@@ -260,14 +322,52 @@ func (b *Bitset) ByteAt(index int) byte {
 		log.Panicf("Index %d out of range", index)
 	}
 
-	var result byte
+	return byte(b.bitsAt(index, min(8, b.numBits-index)))
+}
+
+// BitReader reads the bits of a Bitset in order.
+type BitReader struct {
+	b *Bitset
+
+	// Index of the next bit to read.
+	index int
+}
+
+// NewReader returns a BitReader reading from the start of b.
+func NewReader(b *Bitset) *BitReader {
+	return &BitReader{b: b}
+}
+
+// ReadBits reads the next n bits, and returns them as the n least significant
+// bits of the result. n is at most 64.
+//
+// If fewer than n bits remain, none are read and io.ErrUnexpectedEOF is
+// returned.
+func (r *BitReader) ReadBits(n int) (uint64, error) {
+	if n < 0 || n > 64 {
+		log.Panicf("n %d out of range 0-64", n)
+	}
+
+	if n > r.Remaining() {
+		return 0, io.ErrUnexpectedEOF
+	}
 
-	for i := index; i < index+8 && i < b.numBits; i++ {
-		result <<= 1
-		if b.At(i) {
-			result |= 1
-		}
+	value := r.b.bitsAt(r.index, n)
+	r.index += n
+
+	return value, nil
+}
+
+// Remaining returns the number of bits left to read.
+func (r *BitReader) Remaining() int {
+	return r.b.numBits - r.index
+}
+
+// min returns the minimum of a and b.
+func min(a int, b int) int {
+	if a < b {
+		return a
 	}
 
-	return result
+	return b
 }
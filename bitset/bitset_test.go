@@ -4,6 +4,8 @@
 package bitset
 
 import (
+	"bytes"
+	"io"
 	rand "math/rand"
 	"testing"
 )
@@ -319,3 +321,163 @@ func TestSubstr(t *testing.T) {
 		}
 	}
 }
+
+// randomBitset returns a Bitset of numBits random bits, built from appends of
+// random lengths, and the same bits as bools.
+func randomBitset(rng *rand.Rand, numBits int) (*Bitset, []bool) {
+	b := New()
+	var bits []bool
+
+	for len(bits) < numBits {
+		n := 1 + rng.Intn(20)
+		if n > numBits-len(bits) {
+			n = numBits - len(bits)
+		}
+
+		v := make([]bool, n)
+		for i := range v {
+			v[i] = rng.Intn(2) == 1
+		}
+		bits = append(bits, v...)
+
+		switch rng.Intn(3) {
+		case 0:
+			b.AppendBools(v...)
+		case 1:
+			b.Append(New(v...))
+		default:
+			var value uint32
+			for _, bit := range v {
+				value <<= 1
+				if bit {
+					value |= 1
+				}
+			}
+			b.AppendUint32(value, n)
+		}
+	}
+
+	return b, bits
+}
+
+// packBytes returns bits as bytes, padding a final partial byte with zero bits.
+func packBytes(bits []bool) []byte {
+	result := make([]byte, (len(bits)+7)/8)
+	for i, v := range bits {
+		if v {
+			result[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	return result
+}
+
+func TestWordBoundaries(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		b, bits := randomBitset(rng, rng.Intn(300))
+
+		if !equal(b.Bits(), bits) {
+			t.Fatalf("got %v, want %v", b.Bits(), bits)
+		}
+
+		if !bytes.Equal(b.Bytes(), packBytes(bits)) {
+			t.Fatalf("Bytes() got %x, want %x", b.Bytes(), packBytes(bits))
+		}
+
+		start := rng.Intn(len(bits) + 1)
+		end := start + rng.Intn(len(bits)-start+1)
+
+		if got := b.Substr(start, end).Bits(); !equal(got, bits[start:end]) {
+			t.Fatalf("Substr(%d, %d) got %v, want %v", start, end, got, bits[start:end])
+		}
+
+		if got := b.SubstrBytes(start, end); !bytes.Equal(got, packBytes(bits[start:end])) {
+			t.Fatalf("SubstrBytes(%d, %d) got %x, want %x", start, end, got, packBytes(bits[start:end]))
+		}
+
+		// Unaligned and aligned appends of whole bytes.
+		data := make([]byte, rng.Intn(20))
+		rng.Read(data)
+
+		c := Clone(b)
+		c.AppendBytes(data)
+
+		expected := New(bits...)
+		for _, d := range data {
+			expected.AppendByte(d, 8)
+		}
+
+		if !c.Equals(expected) {
+			t.Fatalf("AppendBytes(%x) got %s, want %s", data, c.String(), expected.String())
+		}
+
+		if b.Len() != len(bits) {
+			t.Fatalf("Clone shares bits: original length changed to %d", b.Len())
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	b := NewFromBase2String("1010 0101 1111 0000 1")
+
+	expected := []byte{0xa5, 0xf0, 0x80}
+	if got := b.Bytes(); !bytes.Equal(got, expected) {
+		t.Errorf("got %x, expected %x", got, expected)
+	}
+
+	expected = []byte{0x4b, 0xe0}
+	if got := b.SubstrBytes(1, 12); !bytes.Equal(got, expected) {
+		t.Errorf("SubstrBytes got %x, expected %x", got, expected)
+	}
+}
+
+func TestBitReader(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	b, bits := randomBitset(rng, 1000)
+
+	r := NewReader(b)
+	for pos := 0; pos < len(bits); {
+		n := rng.Intn(65)
+		if n > r.Remaining() {
+			if _, err := r.ReadBits(n); err != io.ErrUnexpectedEOF {
+				t.Fatalf("ReadBits(%d) with %d bits left got error %v", n, r.Remaining(), err)
+			}
+			n = r.Remaining()
+		}
+
+		value, err := r.ReadBits(n)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var expected uint64
+		for _, v := range bits[pos : pos+n] {
+			expected <<= 1
+			if v {
+				expected |= 1
+			}
+		}
+
+		if value != expected {
+			t.Fatalf("ReadBits(%d) at %d got %x, expected %x", n, pos, value, expected)
+		}
+
+		pos += n
+	}
+
+	if r.Remaining() != 0 {
+		t.Errorf("%d bits remaining after reading all", r.Remaining())
+	}
+}
+
+func BenchmarkAppendBytes(b *testing.B) {
+	data := make([]byte, 3706)
+
+	for i := 0; i < b.N; i++ {
+		result := New()
+		result.AppendBytes(data)
+		_ = result.SubstrBytes(0, result.Len())
+	}
+}
@@ -0,0 +1,243 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// circleLayout is the QR Code laid out within a circle, on a grid of modules
+// with the origin at the top left of the canvas.
+type circleLayout struct {
+	// Width of the canvas, and the circle.
+	size int
+
+	// Position of the top left of the QR Code (including its quiet zone).
+	offset int
+
+	// Radius of the decorated area, within the circle's outline.
+	radius float64
+
+	// Decorative modules, and all modules (including the QR Code's).
+	decoration, bitmap [][]bool
+}
+
+// circleLayout lays out the encoded QR Code within a circle.
+//
+// The circle is just large enough to contain the symbol and a full width
+// quiet zone (even if DisableBorder is set), with a one module outline. The
+// modules between the quiet zone and the outline are set at random, seeded by
+// the content, so are the same each time.
+func (q *QRCode) circleLayout() *circleLayout {
+	realSize := q.symbol.size
+	extra := q.version.quietZoneSize() - q.symbol.quietZoneSize
+
+	// Half the width of the area kept clear, which fits within the circle.
+	half := float64(realSize)/2 + float64(extra)
+
+	size := int(math.Ceil(2*half*math.Sqrt2)) + 2
+	if (size-realSize)%2 != 0 {
+		size++
+	}
+
+	l := &circleLayout{
+		size:       size,
+		offset:     (size - realSize) / 2,
+		radius:     float64(size)/2 - 1,
+		decoration: make([][]bool, size),
+		bitmap:     make([][]bool, size),
+	}
+
+	random := rand.New(rand.NewSource(int64(crc32.ChecksumIEEE([]byte(q.Content)))))
+	symbolBitmap := q.symbol.bitmap()
+
+	clearMin, clearMax := l.offset-extra, l.offset+realSize+extra
+	centre := float64(size) / 2
+
+	for y := 0; y < size; y++ {
+		l.decoration[y] = make([]bool, size)
+		l.bitmap[y] = make([]bool, size)
+
+		for x := 0; x < size; x++ {
+			if x >= l.offset && x < l.offset+realSize && y >= l.offset && y < l.offset+realSize {
+				l.bitmap[y][x] = symbolBitmap[y-l.offset][x-l.offset]
+				continue
+			}
+
+			if x >= clearMin && x < clearMax && y >= clearMin && y < clearMax {
+				continue
+			}
+
+			// The module's furthest corner must be within the circle.
+			dx := math.Max(math.Abs(float64(x)-centre), math.Abs(float64(x+1)-centre))
+			dy := math.Max(math.Abs(float64(y)-centre), math.Abs(float64(y+1)-centre))
+			if math.Hypot(dx, dy) > l.radius {
+				continue
+			}
+
+			v := random.Intn(2) == 1
+			l.decoration[y][x] = v
+			l.bitmap[y][x] = v
+		}
+	}
+
+	return l
+}
+
+// path returns the decorative modules, drawn with shape, s units per module
+// with the origin at (x, y).
+func (l *circleLayout) path(shape ModuleShape, x, y, s float64) *Path {
+	if shape == nil {
+		shape = SquareShape
+	}
+
+	var p Path
+	for j := range l.decoration {
+		for i := range l.decoration[j] {
+			if l.decoration[j][i] {
+				shape.AddModule(&p, x+float64(i)*s, y+float64(j)*s, s, neighbors(l.bitmap, i, j))
+			}
+		}
+	}
+
+	return &p
+}
+
+// outline returns the circle's outline, s units per module with the origin at
+// (x, y).
+func (l *circleLayout) outline(x, y, s float64) *Path {
+	c := float64(l.size) / 2 * s
+
+	var outer, inner Path
+	outer.AddCircle(x+c, y+c, c)
+	inner.AddCircle(x+c, y+c, l.radius*s)
+	outer.Append(inner.reversed())
+
+	return &outer
+}
+
+// CircularImage returns the QR Code drawn within a circle, as drawn by
+// BeautifyImage.
+//
+// The area between the QR Code's quiet zone and the circle's edge is filled
+// with random decorative modules, drawn in the same style as the data
+// modules. The circle is outlined in the BoxColor. Outside the circle, the
+// image is transparent.
+//
+// size is both the image width and height in pixels. Each module is drawn the
+// same whole number of pixels wide, with any leftover pixels added to the
+// margin. Negative values for size set the size of each module instead.
+func (q *QRCode) CircularImage(size int) image.Image {
+	q.encode()
+
+	l := q.circleLayout()
+
+	pixelsPerModule := size / l.size
+	if size < 0 {
+		pixelsPerModule = -size
+		size = l.size * pixelsPerModule
+	}
+	if pixelsPerModule < 1 {
+		pixelsPerModule = 1
+		size = l.size
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	s := float64(pixelsPerModule)
+	margin := float64((size - l.size*pixelsPerModule) / 2)
+	c := float64(l.size) / 2 * s
+
+	var background Path
+	background.AddCircle(margin+c, margin+c, c)
+	background.fill(img, q.BackgroundColor)
+
+	l.outline(margin, margin, s).fill(img, q.BoxColor)
+
+	// The QR Code itself.
+	at := int(margin) + l.offset*pixelsPerModule
+	symbol := q.BeautifyImage(-pixelsPerModule)
+	draw.Draw(img, symbol.Bounds().Add(image.Pt(at, at)), symbol, image.Point{}, draw.Over)
+
+	// The decoration, with any gradient continuing from the QR Code.
+	var src image.Image = image.NewUniform(q.PixelColor)
+	if q.DataGradient != nil {
+		border := float64(at + q.symbol.borderSize()*pixelsPerModule)
+		src = newGradientImage(q.DataGradient, img.Bounds(), border, border, float64(q.symbol.symbolSize)*s)
+	}
+
+	l.path(q.ModuleShape, margin, margin, s).draw(img, src)
+
+	return img
+}
+
+// CircularSVG returns the QR Code drawn within a circle, as an SVG image. See
+// CircularImage for details.
+//
+// size is both the image width and height in pixels. Negative values for size
+// set the size of each module instead.
+func (q *QRCode) CircularSVG(size int) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := q.WriteCircularSVG(&b, size); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteCircularSVG writes the QR Code drawn within a circle, as an SVG image,
+// to w. See CircularSVG for details.
+func (q *QRCode) WriteCircularSVG(w io.Writer, size int) error {
+	q.encode()
+
+	l := q.circleLayout()
+	realSize := q.symbol.size
+
+	if size < 0 {
+		size = size * -1 * l.size
+	}
+
+	if size < l.size {
+		size = l.size
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d">
+`, size, size, l.size, l.size)
+
+	c := formatCoordinate(float64(l.size) / 2)
+	fmt.Fprintf(&b, "<circle cx=\"%s\" cy=\"%s\" r=\"%s\" %s/>\n", c, c, c, svgFill(q.BackgroundColor))
+
+	writeSVGPath(&b, l.outline(0, 0, 1), svgFill(q.BoxColor), false)
+
+	// The decoration is drawn in the QR Code's coordinate system, to share its
+	// gradient.
+	fmt.Fprintf(&b, "<g transform=\"translate(%d %d)\">\n", l.offset, l.offset)
+	fmt.Fprintf(&b, "<rect width=\"%d\" height=\"%d\" %s/>\n", realSize, realSize, svgFill(q.BackgroundColor))
+	q.writeSVGSymbol(&b)
+
+	fill := svgFill(q.PixelColor)
+	if q.DataGradient != nil {
+		fill = `fill="url(#qrcode-data-gradient)"`
+	}
+
+	offset := -float64(l.offset)
+	writeSVGPath(&b, l.path(q.ModuleShape, offset, offset, 1), fill, q.ModuleShape == nil)
+	b.WriteString("</g>\n")
+
+	b.WriteString("</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
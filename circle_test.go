@@ -0,0 +1,116 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image/color"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCircleLayout(t *testing.T) {
+	for _, disableBorder := range []bool{false, true} {
+		q, err := New("https://example.org", Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		q.DisableBorder = disableBorder
+		q.encode()
+
+		l := q.circleLayout()
+
+		// The symbol (29 modules) and a 4 module quiet zone are kept clear.
+		clearMin, clearMax := l.offset+q.symbol.quietZoneSize-4, l.offset+q.symbol.quietZoneSize+29
+		centre := float64(l.size) / 2
+
+		var numDecorations int
+		for y := range l.decoration {
+			for x := range l.decoration[y] {
+				if !l.decoration[y][x] {
+					continue
+				}
+				numDecorations++
+
+				if x >= clearMin && x < clearMax && y >= clearMin && y < clearMax {
+					t.Fatalf("DisableBorder=%t decoration at (%d,%d) in quiet zone", disableBorder, x, y)
+				}
+				if math.Hypot(float64(x)+0.5-centre, float64(y)+0.5-centre) > l.radius {
+					t.Fatalf("DisableBorder=%t decoration at (%d,%d) outside circle", disableBorder, x, y)
+				}
+			}
+		}
+
+		if numDecorations < 100 {
+			t.Errorf("DisableBorder=%t got %d decorations, expected more", disableBorder, numDecorations)
+		}
+
+		if !reflect.DeepEqual(l.decoration, q.circleLayout().decoration) {
+			t.Errorf("DisableBorder=%t decoration differs each time", disableBorder)
+		}
+	}
+}
+
+func TestCircularImage(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img := q.CircularImage(-4)
+	l := q.circleLayout()
+
+	if b := img.Bounds(); b.Dx() != l.size*4 || b.Dy() != l.size*4 {
+		t.Fatalf("got bounds %v, expected %dx%d", b, l.size*4, l.size*4)
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("corner got alpha %x, expected transparent", a)
+	}
+
+	// The outline.
+	if c := color.RGBAModel.Convert(img.At(l.size*2, 1)).(color.RGBA); c != (color.RGBA{0, 0, 0, 0xff}) {
+		t.Errorf("outline got %v, expected black", c)
+	}
+
+	// The QR Code is drawn unchanged.
+	symbol := q.BeautifyImage(-4)
+	at := l.offset * 4
+	for y := 0; y < symbol.Bounds().Dy(); y++ {
+		for x := 0; x < symbol.Bounds().Dx(); x++ {
+			if symbol.At(x, y) != img.At(x+at, y+at) {
+				t.Fatalf("pixel (%d,%d) got %v, expected %v", x, y, img.At(x+at, y+at), symbol.At(x, y))
+			}
+		}
+	}
+
+	// A positive size is kept, with the leftover pixels in the margin.
+	if b := q.CircularImage(500).Bounds(); b.Dx() != 500 || b.Dy() != 500 {
+		t.Errorf("got bounds %v, expected 500x500", b)
+	}
+}
+
+func TestCircularSVG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svg, err := q.CircularSVG(-4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	l := q.circleLayout()
+
+	for _, expected := range []string{
+		`<circle cx="24.5" cy="24.5" r="24.5" fill="#ffffff"/>`,
+		`<g transform="translate(8 8)">`,
+	} {
+		if !strings.Contains(string(svg), expected) {
+			t.Errorf("SVG missing %q (layout size %d, offset %d)", expected, l.size, l.offset)
+		}
+	}
+}
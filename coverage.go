@@ -0,0 +1,295 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import "image"
+
+// CoverageReport is the result of checking whether a QR Code still decodes
+// with some of its modules covered, e.g. by a logo.
+//
+// Each covered data module damages the codeword it belongs to. Each error
+// correction block can correct up to half as many damaged codewords as it has
+// error correction codewords (less a few reserved codewords for the smallest
+// versions).
+type CoverageReport struct {
+	// True if every block can correct all of its damaged codewords.
+	OK bool
+
+	// The number of further codewords the worst affected block could lose and
+	// still be corrected. Negative if a block has more damaged codewords than
+	// it can correct.
+	Margin int
+
+	// Damage to each error correction block.
+	Blocks []BlockCoverage
+
+	// Number of covered function pattern modules (finder, alignment and
+	// timing patterns, and format and version information). These aren't
+	// protected by error correction: Covering the alignment patterns is
+	// usually tolerated, but the finder patterns must stay visible.
+	NumFunctionModulesCovered int
+
+	// Width, in modules, of the largest centred square which can be covered
+	// at this recovery level.
+	MaxLogoModules int
+
+	// The largest CenterLogo size in pixels, including its background offset,
+	// which is safe to draw. Set by AnalyzeLogo only.
+	MaxLogoSize int
+
+	// The lowest recovery level at which the same proportion of the symbol
+	// can be covered. Only valid if HasSuggestedLevel is true.
+	SuggestedLevel    RecoveryLevel
+	HasSuggestedLevel bool
+}
+
+// BlockCoverage is the damage to one error correction block.
+type BlockCoverage struct {
+	// Number of codewords in the block, and how many are data.
+	NumCodewords     int
+	NumDataCodewords int
+
+	// Number of codewords with at least one covered module.
+	NumDamaged int
+
+	// Maximum number of damaged codewords which can be corrected.
+	Capacity int
+}
+
+// AnalyzeCoverage checks whether the QR Code still decodes with the modules
+// where covered[y][x] is true hidden or damaged. covered uses the same layout
+// as Bitmap(), including the quiet zone.
+func (q *QRCode) AnalyzeCoverage(covered [][]bool) *CoverageReport {
+	q.encode()
+
+	c := newCoverageMap(q.version, q.symbol)
+	r := c.analyze(covered)
+	r.MaxLogoModules = c.maxCentredSquare()
+
+	border := q.symbol.quietZoneSize
+	symbolSize := q.symbol.symbolSize
+
+	// The same coverage, scaled to the symbol of each recovery level.
+	for level := Low; level <= Highest; level++ {
+		other, err := NewWithMinimumVersion(q.Content, q.VersionNumber, level)
+		if err != nil {
+			break
+		}
+		other.DisableBorder = q.DisableBorder
+		other.encode()
+
+		scaled := scaleCoverage(covered, border, symbolSize, other.symbol)
+		if newCoverageMap(other.version, other.symbol).analyze(scaled).OK {
+			r.SuggestedLevel = level
+			r.HasSuggestedLevel = true
+			break
+		}
+	}
+
+	return r
+}
+
+// AnalyzeLogo checks whether the QR Code still decodes with the CenterLogo
+// drawn over it by BeautifyImage(size). See AnalyzeCoverage.
+func (q *QRCode) AnalyzeLogo(size int) *CoverageReport {
+	q.encode()
+
+	realSize := q.symbol.size
+
+	if size < 0 {
+		size = size * -1 * realSize
+	}
+
+	if size < realSize {
+		size = realSize
+	}
+
+	covered := make([][]bool, realSize)
+	for i := range covered {
+		covered[i] = make([]bool, realSize)
+	}
+
+	if q.CenterLogo != nil {
+		covered = q.logoCoverage(size)
+	}
+
+	r := q.AnalyzeCoverage(covered)
+
+	// The logo's circular background fits within the square.
+	r.MaxLogoSize = r.MaxLogoModules*size/realSize - 2*q.centerLogoBackgroundOffset
+	if r.MaxLogoSize < 0 {
+		r.MaxLogoSize = 0
+	}
+
+	return r
+}
+
+// coverageMap maps each data module of an encoded symbol to its codeword, and
+// each codeword to its error correction block.
+type coverageMap struct {
+	v qrCodeVersion
+	m *symbol
+
+	// Codeword of each module (in the layout of bitmap()), or -1 for a
+	// function pattern or remainder bit module.
+	codeword []int
+
+	// Block of each codeword.
+	block []int
+}
+
+// newCoverageMap returns the coverage map of the encoded symbol m of version v.
+func newCoverageMap(v qrCodeVersion, m *symbol) *coverageMap {
+	c := &coverageMap{
+		v:        v,
+		m:        m,
+		codeword: make([]int, m.size*m.size),
+	}
+
+	for i := range c.codeword {
+		c.codeword[i] = -1
+	}
+
+	codewords := v.interleavedCodewords()
+	c.block = make([]int, len(codewords))
+	for i, cw := range codewords {
+		c.block[i] = cw.block
+	}
+
+	border := m.quietZoneSize
+	rs := newRegularSymbol(v, 0, nil, false)
+	for i, p := range rs.dataModulePositions(len(codewords) * 8) {
+		c.codeword[(p.Y+border)*m.size+p.X+border] = i / 8
+	}
+
+	return c
+}
+
+// analyze returns the damage to each block, with the modules where
+// covered[y][x] is true damaged.
+func (c *coverageMap) analyze(covered [][]bool) *CoverageReport {
+	return c.analyzeArea(image.Rect(0, 0, c.m.size, c.m.size), func(x, y int) bool {
+		return y < len(covered) && x < len(covered[y]) && covered[y][x]
+	})
+}
+
+// analyzeArea returns the damage to each block, with the modules in area
+// where isCovered(x, y) is true damaged.
+func (c *coverageMap) analyzeArea(area image.Rectangle, isCovered func(x, y int) bool) *CoverageReport {
+	r := &CoverageReport{}
+
+	for _, b := range c.v.block {
+		for j := 0; j < b.numBlocks; j++ {
+			numEC := b.numCodewords - b.numDataCodewords
+
+			r.Blocks = append(r.Blocks, BlockCoverage{
+				NumCodewords:     b.numCodewords,
+				NumDataCodewords: b.numDataCodewords,
+				Capacity:         (numEC - c.v.numMisdecodeProtectionCodewords()) / 2,
+			})
+		}
+	}
+
+	damaged := make([]bool, len(c.block))
+
+	area = area.Intersect(image.Rect(0, 0, c.m.size, c.m.size))
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if !isCovered(x, y) {
+				continue
+			}
+
+			if c.m.functionModule.get(x, y) {
+				r.NumFunctionModulesCovered++
+			} else if i := c.codeword[y*c.m.size+x]; i >= 0 && !damaged[i] {
+				damaged[i] = true
+				r.Blocks[c.block[i]].NumDamaged++
+			}
+		}
+	}
+
+	for i, b := range r.Blocks {
+		margin := b.Capacity - b.NumDamaged
+		if i == 0 || margin < r.Margin {
+			r.Margin = margin
+		}
+	}
+	r.OK = r.Margin >= 0
+
+	return r
+}
+
+// maxCentredSquare returns the width, in modules, of the largest square at the
+// centre of the symbol which can be covered. The square may cover alignment
+// patterns, but no other function patterns.
+func (c *coverageMap) maxCentredSquare() int {
+	border := c.m.quietZoneSize
+	covered := func(x, y int) bool { return true }
+
+	maxSize := 0
+	for k := 1; k <= c.m.symbolSize; k++ {
+		min := border + (c.m.symbolSize-k)/2
+		square := image.Rect(min, min, min+k, min+k)
+
+		for y := square.Min.Y; y < square.Max.Y; y++ {
+			for x := square.Min.X; x < square.Max.X; x++ {
+				if c.m.functionModule.get(x, y) && !c.m.alignmentPatternModule.get(x, y) {
+					return maxSize
+				}
+			}
+		}
+
+		if !c.analyzeArea(square, covered).OK {
+			break
+		}
+		maxSize = k
+	}
+
+	return maxSize
+}
+
+// centredSquare returns a size*size coverage bitmap with a k*k module square
+// covered at the centre of the symbol. border is the quiet zone size.
+func centredSquare(size int, border int, k int) [][]bool {
+	covered := make([][]bool, size)
+	for i := range covered {
+		covered[i] = make([]bool, size)
+	}
+
+	min := border + (size-2*border-k)/2
+	for y := min; y < min+k; y++ {
+		for x := min; x < min+k; x++ {
+			covered[y][x] = true
+		}
+	}
+
+	return covered
+}
+
+// scaleCoverage scales covered, for a symbolSize module symbol with a quiet
+// zone of border modules, to the symbol m. Each module of m is covered if its
+// centre is at a covered module of the original.
+func scaleCoverage(covered [][]bool, border int, symbolSize int, m *symbol) [][]bool {
+	scaled := make([][]bool, m.size)
+	for i := range scaled {
+		scaled[i] = make([]bool, m.size)
+	}
+
+	scale := float64(symbolSize) / float64(m.symbolSize)
+	source := func(i int) int {
+		return border + int((float64(i-m.quietZoneSize)+0.5)*scale)
+	}
+
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			sx, sy := source(x), source(y)
+
+			if m.inSymbol(x, y) && sy < len(covered) && sx < len(covered[sy]) {
+				scaled[y][x] = covered[sy][sx]
+			}
+		}
+	}
+
+	return scaled
+}
@@ -0,0 +1,157 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestInterleavedCodewords(t *testing.T) {
+	// 5-Q has two blocks of 15 data codewords, then two of 16, each with 18
+	// error correction codewords.
+	v := getQRCodeVersion(High, 5)
+	codewords := v.interleavedCodewords()
+
+	if len(codewords) != 134 {
+		t.Fatalf("got %d codewords, expected 134", len(codewords))
+	}
+
+	expected := map[int]blockCodeword{
+		0:   {0, 0},
+		3:   {3, 0},
+		4:   {0, 1},
+		59:  {3, 14},
+		60:  {2, 15},
+		61:  {3, 15},
+		62:  {0, 15},
+		63:  {1, 15},
+		64:  {2, 16},
+		133: {3, 33},
+	}
+
+	for i, c := range expected {
+		if codewords[i] != c {
+			t.Errorf("codeword %d got %v, expected %v", i, codewords[i], c)
+		}
+	}
+
+	seen := make(map[blockCodeword]bool)
+	for _, c := range codewords {
+		if seen[c] {
+			t.Errorf("codeword %v repeated", c)
+		}
+		seen[c] = true
+	}
+}
+
+func TestAnalyzeCoverage(t *testing.T) {
+	q, err := New("01234567", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Version 1-M: A single block with 10 error correction codewords, 2 of
+	// which are for misdecode protection.
+	bitmap := q.Bitmap()
+	size := len(bitmap)
+
+	r := q.AnalyzeCoverage(centredSquare(size, 4, 0))
+	if !r.OK || r.Margin != 4 || len(r.Blocks) != 1 || r.Blocks[0].Capacity != 4 {
+		t.Errorf("uncovered got %+v, expected OK with margin 4", r)
+	}
+
+	if r.MaxLogoModules < 2 || r.MaxLogoModules > 6 {
+		t.Errorf("got max logo %d modules, expected 2-6", r.MaxLogoModules)
+	}
+	if !r.HasSuggestedLevel || r.SuggestedLevel != Low {
+		t.Errorf("got suggested level %v, expected Low", r.SuggestedLevel)
+	}
+
+	r = q.AnalyzeCoverage(centredSquare(size, 4, r.MaxLogoModules+1))
+	if r.OK || r.Margin >= 0 {
+		t.Errorf("covered got OK margin %d, expected failure", r.Margin)
+	}
+	if !r.HasSuggestedLevel || r.SuggestedLevel <= Medium {
+		t.Errorf("got suggested level %v (%t), expected a higher level", r.SuggestedLevel, r.HasSuggestedLevel)
+	}
+
+	// Covering exactly the first codeword's modules damages only it.
+	covered := centredSquare(size, 4, 0)
+	m := newRegularSymbol(q.version, 0, nil, false)
+	for _, p := range m.dataModulePositions(8) {
+		covered[p.Y+4][p.X+4] = true
+	}
+
+	r = q.AnalyzeCoverage(covered)
+	if r.Blocks[0].NumDamaged != 1 || r.NumFunctionModulesCovered != 0 {
+		t.Errorf("got %d damaged codewords, %d function modules, expected 1 and 0",
+			r.Blocks[0].NumDamaged, r.NumFunctionModulesCovered)
+	}
+
+	// Covering a finder pattern is reported separately.
+	covered = centredSquare(size, 4, 0)
+	for y := 4; y < 11; y++ {
+		for x := 4; x < 11; x++ {
+			covered[y][x] = true
+		}
+	}
+
+	r = q.AnalyzeCoverage(covered)
+	if !r.OK || r.NumFunctionModulesCovered != 49 {
+		t.Errorf("got OK %t, %d function modules covered, expected true and 49",
+			r.OK, r.NumFunctionModulesCovered)
+	}
+}
+
+func TestAnalyzeCoverageFunctionPatterns(t *testing.T) {
+	q, err := NewWithForcedVersion("1", 1, Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Error correction could recover a larger square, but a 4x4 square would
+	// cover the format information at (8,8).
+	r := q.AnalyzeCoverage(centredSquare(len(q.Bitmap()), 4, 0))
+	if r.MaxLogoModules != 3 {
+		t.Errorf("got max logo %d modules, expected 3", r.MaxLogoModules)
+	}
+
+	if r := q.AnalyzeCoverage(centredSquare(len(q.Bitmap()), 4, 4)); !r.OK || r.NumFunctionModulesCovered != 1 {
+		t.Errorf("4x4 square got OK %t, %d function modules covered, expected true and 1",
+			r.OK, r.NumFunctionModulesCovered)
+	}
+}
+
+func TestAnalyzeLogo(t *testing.T) {
+	q, err := New("https://example.org", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var logo image.Image = rectangleImage(50, 50, color.Black)
+	q.CenterLogo = &logo
+
+	// The logo covers about 6x6 modules, more than the low recovery level can
+	// correct.
+	r := q.AnalyzeLogo(256)
+	if r.OK {
+		t.Errorf("large logo at Low got OK margin %d, expected failure", r.Margin)
+	}
+	if !r.HasSuggestedLevel || r.SuggestedLevel != High {
+		t.Errorf("got suggested level %v (%t), expected High", r.SuggestedLevel, r.HasSuggestedLevel)
+	}
+	if r.MaxLogoSize <= 0 || r.MaxLogoSize >= 50 {
+		t.Errorf("got max logo size %dpx, expected smaller than the logo", r.MaxLogoSize)
+	}
+
+	// A logo of the suggested size is OK.
+	var small image.Image = rectangleImage(r.MaxLogoSize, r.MaxLogoSize, color.Black)
+	q.CenterLogo = &small
+
+	if r := q.AnalyzeLogo(256); !r.OK {
+		t.Errorf("%dpx logo got failure margin %d, expected OK", r.MaxLogoSize, r.Margin)
+	}
+}
@@ -0,0 +1,274 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// DarkModeStyle is the colour scheme of a QR Code shown in dark mode.
+//
+// Inverted QR Codes (light modules on a dark background) aren't read by all
+// scanners. Instead, the QR Code keeps dark modules on a light background: A
+// "card" formed by the quiet zone, dimmed to suit a dark page.
+type DarkModeStyle struct {
+	// Colour of the card. Defaults to light gray.
+	BackgroundColor color.Color
+
+	// Colours of the modules. Default to the QRCode's PixelColor and BoxColor.
+	// Gradients and finder and alignment pattern colours aren't used in dark
+	// mode.
+	PixelColor color.Color
+	BoxColor   color.Color
+
+	// Radius of the card's corners in modules, in both light and dark mode.
+	// Defaults to square corners.
+	CornerRadius float64
+}
+
+// defaultDarkModeBackgroundColor is the default card colour in dark mode.
+var defaultDarkModeBackgroundColor = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+
+// darkModeQRCode returns a copy of q drawn with the dark mode colours of s.
+// An error is returned if the colours are inverted, or have too little
+// contrast.
+func (q *QRCode) darkModeQRCode(s *DarkModeStyle) (*QRCode, error) {
+	d := *q
+
+	d.BackgroundColor = defaultDarkModeBackgroundColor
+	if s.BackgroundColor != nil {
+		d.BackgroundColor = s.BackgroundColor
+	}
+	if s.PixelColor != nil {
+		d.PixelColor = s.PixelColor
+	}
+	if s.BoxColor != nil {
+		d.BoxColor = s.BoxColor
+	}
+
+	d.DataGradient, d.FinderGradient = nil, nil
+	d.BackgroundImage = nil
+
+	if q.FinderStyle != nil {
+		style := *q.FinderStyle
+		style.OuterColor, style.InnerColor = nil, nil
+		d.FinderStyle = &style
+	}
+	if q.AlignmentStyle != nil {
+		style := *q.AlignmentStyle
+		style.OuterColor, style.InnerColor = nil, nil
+		d.AlignmentStyle = &style
+	}
+
+	// The cached images include the light mode background colour.
+	d.centerLogoCache = nil
+	d.styledLogoCache = nil
+	d.finderPatternImageCache = nil
+	d.alignmentPatternImageCache = nil
+	d.backgroundImageCache = nil
+
+	background := flattenColor(d.BackgroundColor)
+	for _, c := range []color.Color{d.PixelColor, d.BoxColor} {
+		module := compositeColor(c, background)
+
+		if relativeLuminance(module) >= relativeLuminance(background) {
+			return nil, errors.New("dark mode colours are inverted: the background must be lighter than the modules")
+		}
+		if contrastRatio(module, background) < minContrastRatio {
+			return nil, fmt.Errorf("low contrast with the dark mode background colour: %.2f:1 (minimum %.0f:1)",
+				contrastRatio(module, background), float64(minContrastRatio))
+		}
+	}
+
+	return &d, nil
+}
+
+// cardSize returns the width of the card in modules, and the width of the
+// quiet zone added around the symbol. The card always includes a full width
+// quiet zone, even if DisableBorder is set.
+func (q *QRCode) cardSize() (size int, extra int) {
+	extra = q.version.quietZoneSize() - q.symbol.quietZoneSize
+
+	return q.symbol.size + 2*extra, extra
+}
+
+// LightDarkImages returns a matched pair of images of the QR Code, as drawn by
+// BeautifyImage: One for light mode, and one with the dark mode colours of
+// style (which may be nil for the defaults).
+//
+// Each QR Code is drawn on a card including its quiet zone, with the corners
+// rounded by style.CornerRadius. Outside the card, the images are transparent.
+//
+// size is both the image width and height in pixels. Each module is drawn the
+// same whole number of pixels wide, so the images may be smaller than size.
+// Negative values for size set the size of each module instead.
+func (q *QRCode) LightDarkImages(size int, style *DarkModeStyle) (light, dark image.Image, err error) {
+	q.encode()
+
+	if style == nil {
+		style = &DarkModeStyle{}
+	}
+
+	d, err := q.darkModeQRCode(style)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cardSize, _ := q.cardSize()
+
+	pixelsPerModule := size / cardSize
+	if size < 0 {
+		pixelsPerModule = -size
+	}
+	if pixelsPerModule < 1 {
+		pixelsPerModule = 1
+	}
+
+	return q.cardImage(pixelsPerModule, style.CornerRadius), d.cardImage(pixelsPerModule, style.CornerRadius), nil
+}
+
+// LightDarkPNG returns a matched pair of PNG images of the QR Code, for light
+// and dark mode. See LightDarkImages for details.
+func (q *QRCode) LightDarkPNG(size int, style *DarkModeStyle) (light, dark []byte, err error) {
+	lightImage, darkImage, err := q.LightDarkImages(size, style)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+
+	var l, d bytes.Buffer
+	if err := encoder.Encode(&l, lightImage); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Encode(&d, darkImage); err != nil {
+		return nil, nil, err
+	}
+
+	return l.Bytes(), d.Bytes(), nil
+}
+
+// cardImage returns the QR Code drawn on its card, pixelsPerModule pixels per
+// module, with the card's corners rounded by radius modules.
+func (q *QRCode) cardImage(pixelsPerModule int, radius float64) image.Image {
+	cardSize, extra := q.cardSize()
+	size := cardSize * pixelsPerModule
+
+	card := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(card, card.Bounds(), image.NewUniform(q.BackgroundColor), image.Point{}, draw.Src)
+
+	at := extra * pixelsPerModule
+	symbol := q.BeautifyImage(-pixelsPerModule)
+	draw.Draw(card, symbol.Bounds().Add(image.Pt(at, at)), symbol, image.Point{}, draw.Src)
+
+	// The card, clipped to its rounded corners.
+	img := image.NewRGBA(card.Bounds())
+
+	var clip Path
+	clip.AddRoundedRect(0, 0, float64(size), float64(size), uniformRadii(radius*float64(pixelsPerModule)))
+	clip.draw(img, card)
+
+	return img
+}
+
+// DarkModeSVG returns the QR Code as an SVG image, which switches to the dark
+// mode colours of style (which may be nil for the defaults) when the viewer
+// prefers a dark colour scheme. See DarkModeStyle.
+//
+// The QR Code is drawn on a card including its quiet zone, with the corners
+// rounded by style.CornerRadius. Outside the card, the image is transparent.
+//
+// size is both the image width and height in pixels. Negative values for size
+// set the size of each module instead.
+func (q *QRCode) DarkModeSVG(size int, style *DarkModeStyle) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := q.WriteDarkModeSVG(&b, size, style); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteDarkModeSVG writes the QR Code as a dark mode aware SVG image to w. See
+// DarkModeSVG for details.
+func (q *QRCode) WriteDarkModeSVG(w io.Writer, size int, style *DarkModeStyle) error {
+	q.encode()
+
+	if style == nil {
+		style = &DarkModeStyle{}
+	}
+
+	d, err := q.darkModeQRCode(style)
+	if err != nil {
+		return err
+	}
+
+	cardSize, extra := q.cardSize()
+
+	if size < 0 {
+		size = size * -1 * cardSize
+	}
+
+	if size < cardSize {
+		size = cardSize
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d">
+`, size, size, cardSize, cardSize)
+
+	// CSS rules take precedence over the fill attributes.
+	fmt.Fprintf(&b, `<style>
+@media (prefers-color-scheme: dark) {
+.qrcode-card { %s }
+.qrcode-box { %s }
+.qrcode-pixel { %s }
+}
+</style>
+`, cssFill(d.BackgroundColor), cssFill(d.BoxColor), cssFill(d.PixelColor))
+
+	var rounding string
+	if style.CornerRadius > 0 {
+		r := formatCoordinate(style.CornerRadius)
+		rounding = fmt.Sprintf(` rx="%s" ry="%s"`, r, r)
+	}
+
+	fmt.Fprintf(&b, "<rect class=\"qrcode-card\" width=\"%d\" height=\"%d\"%s %s/>\n",
+		cardSize, cardSize, rounding, svgFill(q.BackgroundColor))
+
+	fmt.Fprintf(&b, "<g transform=\"translate(%d %d)\">\n", extra, extra)
+	q.writeSVGGradients(&b)
+
+	for _, l := range q.symbolLayers() {
+		class := "qrcode-pixel"
+		if l.finder {
+			class = "qrcode-box"
+		}
+
+		writeSVGPath(&b, l.path, fmt.Sprintf(`class="%s" %s`, class, l.fill), l.crisp)
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString("</svg>\n")
+
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// cssFill returns the CSS declarations to fill with c.
+func cssFill(c color.Color) string {
+	hex, opacity := svgColor(c)
+
+	return fmt.Sprintf("fill: %s; fill-opacity: %s;", hex, formatCoordinate(opacity))
+}
@@ -0,0 +1,132 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestLightDarkImages(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.DisableBorder = true
+	q.PixelColor = color.RGBA{0x20, 0x20, 0x60, 0xff}
+
+	light, dark, err := q.LightDarkImages(-10, &DarkModeStyle{CornerRadius: 2})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 25 modules, with a full 4 module quiet zone restored.
+	for _, img := range []struct {
+		name       string
+		image      image.Image
+		background color.RGBA
+	}{
+		{"light", light, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{"dark", dark, color.RGBA{0xe0, 0xe0, 0xe0, 0xff}},
+	} {
+		if b := img.image.Bounds(); b.Dx() != 330 || b.Dy() != 330 {
+			t.Fatalf("%s got bounds %v, expected 330x330", img.name, b)
+		}
+
+		at := func(x, y int) color.RGBA {
+			return color.RGBAModel.Convert(img.image.At(x, y)).(color.RGBA)
+		}
+
+		if c := at(0, 0); c.A != 0 {
+			t.Errorf("%s corner got %v, expected transparent", img.name, c)
+		}
+		if c := at(5, 165); c != img.background {
+			t.Errorf("%s quiet zone got %v, expected %v", img.name, c, img.background)
+		}
+
+		// The top left finder pattern.
+		if c := at(45, 45); c != (color.RGBA{0, 0, 0, 0xff}) {
+			t.Errorf("%s finder pattern got %v, expected black", img.name, c)
+		}
+	}
+
+	// A light module on a dark card is rejected.
+	_, _, err = q.LightDarkImages(-10, &DarkModeStyle{
+		BackgroundColor: color.RGBA{0x20, 0x20, 0x20, 0xff},
+		PixelColor:      color.RGBA{0xff, 0xff, 0xff, 0xff},
+	})
+	if err == nil || !strings.Contains(err.Error(), "inverted") {
+		t.Errorf("inverted colours got error %v, expected inverted", err)
+	}
+
+	// As is a card too dark for the modules.
+	_, _, err = q.LightDarkImages(-10, &DarkModeStyle{BackgroundColor: color.RGBA{0x50, 0x50, 0x50, 0xff}})
+	if err == nil || !strings.Contains(err.Error(), "low contrast") {
+		t.Errorf("dark card got error %v, expected low contrast", err)
+	}
+}
+
+func TestLightDarkPNG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	light, dark, err := q.LightDarkPNG(330, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, data := range [][]byte{light, dark} {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if b := img.Bounds(); b.Dx() != 330 || b.Dy() != 330 {
+			t.Errorf("got bounds %v, expected 330x330", b)
+		}
+	}
+
+	if bytes.Equal(light, dark) {
+		t.Error("light and dark images are the same")
+	}
+}
+
+func TestDarkModeSVG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.DisableBorder = true
+
+	svg, err := q.DarkModeSVG(-10, &DarkModeStyle{
+		BackgroundColor: color.RGBA{0xc0, 0xc0, 0xc0, 0xff},
+		PixelColor:      color.RGBA{0x10, 0x10, 0x40, 0xff},
+		CornerRadius:    1.5,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, expected := range []string{
+		`width="330" height="330" viewBox="0 0 33 33"`,
+		`@media (prefers-color-scheme: dark)`,
+		`.qrcode-card { fill: #c0c0c0; fill-opacity: 1; }`,
+		`.qrcode-box { fill: #000000; fill-opacity: 1; }`,
+		`.qrcode-pixel { fill: #101040; fill-opacity: 1; }`,
+		`<rect class="qrcode-card" width="33" height="33" rx="1.5" ry="1.5" fill="#ffffff"/>`,
+		`<g transform="translate(4 4)">`,
+		`class="qrcode-box" fill="#000000"`,
+		`class="qrcode-pixel" fill="#000000"`,
+	} {
+		if !strings.Contains(string(svg), expected) {
+			t.Errorf("SVG missing %q", expected)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// EyeShape is the shape of part of a finder ("eye") pattern.
+type EyeShape int
+
+const (
+	// EyeSquare is the standard square shape.
+	EyeSquare EyeShape = iota
+
+	// EyeRounded is a square with rounded corners.
+	EyeRounded
+
+	// EyeCircle is a circle.
+	EyeCircle
+
+	// EyeLeaf is a square with two opposite corners fully rounded. The
+	// rounded corners face towards and away from the centre of the symbol.
+	EyeLeaf
+)
+
+// EyeStyle styles the three finder ("eye") patterns, or the alignment
+// patterns, of a QR Code.
+//
+// A finder pattern consists of a 7x7 module outer ring, and a 3x3 module inner
+// "pupil". An alignment pattern is a 5x5 module ring and a single module
+// pupil. Each is drawn with its own shape and colour, in vector form, so they
+// are sharp at any size.
+type EyeStyle struct {
+	// Shape of the outer ring.
+	Outer EyeShape
+
+	// Shape of the inner pupil.
+	Inner EyeShape
+
+	// Colour of the outer ring. Defaults to the QRCode's BoxColor (or
+	// FinderGradient) for finder patterns, and PixelColor (or DataGradient)
+	// for alignment patterns.
+	OuterColor color.Color
+
+	// Colour of the inner pupil. Defaults as OuterColor.
+	InnerColor color.Color
+}
+
+// sources returns the source images to draw the outer ring and inner pupil
+// with. Unset colours are drawn with def.
+func (e *EyeStyle) sources(def image.Image) (image.Image, image.Image) {
+	outer, inner := def, def
+
+	if e.OuterColor != nil {
+		outer = image.NewUniform(e.OuterColor)
+	}
+	if e.InnerColor != nil {
+		inner = image.NewUniform(e.InnerColor)
+	}
+
+	return outer, inner
+}
+
+// svgFills returns the SVG fill attributes of the outer ring and inner pupil.
+// Unset colours are filled with def.
+func (e *EyeStyle) svgFills(def string) (string, string) {
+	outer, inner := def, def
+
+	if e.OuterColor != nil {
+		outer = svgFill(e.OuterColor)
+	}
+	if e.InnerColor != nil {
+		inner = svgFill(e.InnerColor)
+	}
+
+	return outer, inner
+}
+
+// colors returns the colours of the outer ring and inner pupil. Unset colours
+// default to def.
+func (e *EyeStyle) colors(def color.Color) (color.Color, color.Color) {
+	outer, inner := def, def
+
+	if e.OuterColor != nil {
+		outer = e.OuterColor
+	}
+	if e.InnerColor != nil {
+		inner = e.InnerColor
+	}
+
+	return outer, inner
+}
+
+// Corner indexes, as used by Path.AddRoundedRect.
+const (
+	cornerTopLeft = iota
+	cornerTopRight
+	cornerBottomRight
+	cornerBottomLeft
+)
+
+// eyeRadii returns the corner radii of a w*w eye shape. leafCorner is the
+// outward facing corner of the finder pattern, which (with the opposite
+// corner) is rounded by EyeLeaf.
+func eyeRadii(shape EyeShape, w float64, leafCorner int) [4]float64 {
+	var r [4]float64
+
+	switch shape {
+	case EyeRounded:
+		for i := range r {
+			r[i] = w * 2 / 7
+		}
+	case EyeCircle:
+		for i := range r {
+			r[i] = w / 2
+		}
+	case EyeLeaf:
+		r[leafCorner] = w / 2
+		r[(leafCorner+2)%4] = w / 2
+	}
+
+	return r
+}
+
+// addEye adds the outlines of an n*n module finder or alignment pattern with
+// top left corner (x, y), at module size s. The outer ring (one module thick)
+// is added to outer, and the pupil to inner.
+func (e *EyeStyle) addEye(outer, inner *Path, x, y, s float64, n int, leafCorner int) {
+	w := float64(n) * s
+
+	r := eyeRadii(e.Outer, w, leafCorner)
+	outer.AddRoundedRect(x, y, w, w, r)
+
+	// The ring's hole is concentric with its outside edge.
+	for i := range r {
+		r[i] = math.Max(0, r[i]-s)
+	}
+
+	var hole Path
+	hole.AddRoundedRect(x+s, y+s, w-2*s, w-2*s, r)
+	outer.Append(hole.reversed())
+
+	inner.AddRoundedRect(x+2*s, y+2*s, w-4*s, w-4*s, eyeRadii(e.Inner, w-4*s, leafCorner))
+}
+
+// addFinderEyes adds the outlines of all three finder patterns of m. pos maps
+// a module position (including the quiet zone) to drawing coordinates, and s
+// is the module size in drawing coordinates.
+func (e *EyeStyle) addFinderEyes(m *symbol, outer, inner *Path,
+	pos func(x, y int) (float64, float64), s float64) {
+
+	tl, tr, bl := m.finderPatternPoints()
+	border := m.borderSize()
+
+	for _, f := range []struct {
+		x, y   int
+		corner int
+	}{
+		{tl.X, tl.Y, cornerTopLeft},
+		{tr.X, tr.Y, cornerTopRight},
+		{bl.X, bl.Y, cornerBottomLeft},
+	} {
+		x, y := pos(f.x+border, f.y+border)
+		e.addEye(outer, inner, x, y, s, m.finderPatternSize, f.corner)
+	}
+}
+
+// addAlignmentEyes adds the outlines of all alignment patterns of m. See
+// addFinderEyes.
+func (e *EyeStyle) addAlignmentEyes(m *symbol, outer, inner *Path,
+	pos func(x, y int) (float64, float64), s float64) {
+
+	border := m.borderSize()
+
+	for _, p := range m.alignmentPatternPoints {
+		x, y := pos(p.X+border, p.Y+border)
+		e.addEye(outer, inner, x, y, s, m.alignmentPatternSize, cornerTopLeft)
+	}
+}
@@ -0,0 +1,144 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestFinderStyle(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	// At 10px per module, with a 4 module quiet zone, the top left finder
+	// pattern is at 40-110px. The 33 module symbol's top right finder pattern
+	// is at 220-290px.
+	tests := []struct {
+		style EyeStyle
+		at    [][2]int
+		want  []color.RGBA
+	}{
+		{
+			EyeStyle{Outer: EyeSquare, Inner: EyeSquare, OuterColor: red, InnerColor: blue},
+			[][2]int{{75, 75}, {75, 45}, {41, 41}, {55, 75}},
+			[]color.RGBA{blue, red, red, white},
+		},
+		{
+			EyeStyle{Outer: EyeCircle, Inner: EyeCircle, OuterColor: red, InnerColor: blue},
+			[][2]int{{75, 75}, {75, 45}, {41, 41}, {61, 61}, {55, 75}},
+			[]color.RGBA{blue, red, white, white, white},
+		},
+		{
+			EyeStyle{Outer: EyeLeaf, Inner: EyeSquare, OuterColor: red, InnerColor: blue},
+			[][2]int{{41, 41}, {108, 41}, {108, 108}, {221, 41}, {288, 41}},
+			[]color.RGBA{white, red, white, red, white},
+		},
+		{
+			EyeStyle{Outer: EyeRounded, Inner: EyeRounded},
+			[][2]int{{40, 40}, {75, 41}, {75, 75}},
+			[]color.RGBA{white, {0, 0, 0, 0xff}, {0, 0, 0, 0xff}},
+		},
+	}
+
+	for i, test := range tests {
+		q, err := New("https://example.org", Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		q.FinderStyle = &test.style
+
+		img := q.BeautifyImage(-10)
+
+		for j, p := range test.at {
+			got := color.RGBAModel.Convert(img.At(p[0], p[1])).(color.RGBA)
+
+			if got != test.want[j] {
+				t.Errorf("test %d: pixel %v got %v, expected %v", i, p, got, test.want[j])
+			}
+		}
+	}
+}
+
+func TestFinderStyleSVG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.FinderStyle = &EyeStyle{
+		Outer:      EyeCircle,
+		Inner:      EyeLeaf,
+		OuterColor: color.RGBA{0xff, 0, 0, 0xff},
+		InnerColor: color.RGBA{0, 0, 0xff, 0xff},
+	}
+
+	b, err := q.SVG(-4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	doc := parseSVG(t, b)
+
+	var numOuter, numInner int
+	for _, p := range doc.Paths {
+		switch p.Fill {
+		case "#ff0000":
+			numOuter++
+
+			// Three rings, each an outside edge and a hole.
+			if n := strings.Count(p.D, "M"); n != 6 {
+				t.Errorf("outer ring path has %d subpaths, expected 6", n)
+			}
+		case "#0000ff":
+			numInner++
+
+			if n := strings.Count(p.D, "M"); n != 3 {
+				t.Errorf("pupil path has %d subpaths, expected 3", n)
+			}
+		}
+	}
+
+	if numOuter != 1 || numInner != 1 {
+		t.Errorf("got %d outer and %d inner paths, expected 1 of each", numOuter, numInner)
+	}
+}
+
+func TestAlignmentStyle(t *testing.T) {
+	q, err := NewWithForcedVersion("https://example.org", 7, Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	q.AlignmentStyle = &EyeStyle{Outer: EyeRounded, Inner: EyeCircle, OuterColor: red, InnerColor: blue}
+
+	img := q.BeautifyImage(-10)
+
+	// Every alignment pattern is styled, not just the bottom right one.
+	border := q.symbol.borderSize()
+	for _, p := range q.symbol.alignmentPatternPoints {
+		x, y := (p.X+border)*10, (p.Y+border)*10
+
+		if got := color.RGBAModel.Convert(img.At(x+25, y+25)); got != blue {
+			t.Errorf("alignment pattern %v pupil got %v, expected %v", p, got, blue)
+		}
+		if got := color.RGBAModel.Convert(img.At(x+25, y+5)); got != red {
+			t.Errorf("alignment pattern %v ring got %v, expected %v", p, got, red)
+		}
+	}
+
+	b, err := q.SVG(-4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, p := range parseSVG(t, b).Paths {
+		if p.Fill == "#0000ff" && strings.Count(p.D, "M") != 6 {
+			t.Errorf("pupil path has %d subpaths, expected 6", strings.Count(p.D, "M"))
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+)
+
+// Format is an image file format a QR Code can be written in.
+type Format int
+
+const (
+	// FormatPNG is an 8-bit paletted PNG image, as returned by PNG().
+	FormatPNG Format = iota
+
+	// FormatPNG1Bit is a 1-bit grayscale PNG image. Colours are reduced to
+	// black and white, which roughly halves the file size.
+	FormatPNG1Bit
+
+	// FormatGIF is a paletted GIF image.
+	FormatGIF
+
+	// FormatJPEG is a JPEG image. The quality is set by QRCode.JPEGQuality.
+	FormatJPEG
+
+	// FormatBMP is a Windows bitmap image.
+	FormatBMP
+
+	// FormatPBM is a binary Netpbm bitmap (P4). Colours are reduced to black
+	// and white.
+	FormatPBM
+
+	// FormatPGM is a binary Netpbm graymap (P5).
+	FormatPGM
+
+	// FormatSVG is an SVG vector image, as returned by SVG().
+	FormatSVG
+)
+
+// String returns the format's common name.
+func (f Format) String() string {
+	switch f {
+	case FormatPNG:
+		return "PNG"
+	case FormatPNG1Bit:
+		return "PNG (1-bit)"
+	case FormatGIF:
+		return "GIF"
+	case FormatJPEG:
+		return "JPEG"
+	case FormatBMP:
+		return "BMP"
+	case FormatPBM:
+		return "PBM"
+	case FormatPGM:
+		return "PGM"
+	case FormatSVG:
+		return "SVG"
+	}
+
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// FormatFromFilename returns the Format matching filename's extension.
+//
+// The extensions recognised are .png, .gif, .jpg, .jpeg, .bmp, .pbm, .pgm and
+// .svg.
+// PNG files are written in the 8-bit paletted format.
+func FormatFromFilename(filename string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png":
+		return FormatPNG, nil
+	case ".gif":
+		return FormatGIF, nil
+	case ".jpg", ".jpeg":
+		return FormatJPEG, nil
+	case ".bmp":
+		return FormatBMP, nil
+	case ".pbm":
+		return FormatPBM, nil
+	case ".pgm":
+		return FormatPGM, nil
+	case ".svg":
+		return FormatSVG, nil
+	}
+
+	return FormatPNG, fmt.Errorf("unknown image format for filename %q", filename)
+}
+
+// Encode writes the QR Code to w in the given image format.
+//
+// size is both the image width and height in pixels. If size is too small then
+// a larger image is silently written. Negative values for size cause a
+// variable sized image to be written: See the documentation for Image().
+func (q *QRCode) Encode(w io.Writer, format Format, size int) error {
+	if format == FormatSVG {
+		return q.WriteSVG(w, size)
+	}
+
+	return q.encodeImage(w, q.Image(size), format, 0)
+}
+
+// EncodeImage writes img, an image of the QR Code (e.g. from BeautifyImage or
+// FrameImage), to w in the given image format. FormatSVG isn't supported for
+// images: Use SVG or FramedSVG instead.
+func (q *QRCode) EncodeImage(w io.Writer, img image.Image, format Format) error {
+	return q.encodeImage(w, img, format, 0)
+}
+
+// encodeImage writes img to w in the given image format.
+//
+// A positive dpi is recorded in the image's resolution metadata, for the
+// formats which have it (PNG, JPEG and BMP).
+func (q *QRCode) encodeImage(w io.Writer, img image.Image, format Format, dpi int) error {
+	switch format {
+	case FormatPNG1Bit, FormatJPEG, FormatBMP, FormatPBM, FormatPGM:
+		// These formats have no alpha channel.
+		img = flattenImage(img)
+	case FormatGIF:
+		img = flattenGIFImage(img)
+	}
+
+	switch format {
+	case FormatPNG:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if dpi <= 0 {
+			return encoder.Encode(w, img)
+		}
+
+		var b bytes.Buffer
+		if err := encoder.Encode(&b, img); err != nil {
+			return err
+		}
+		return writePNGWithDPI(w, b.Bytes(), dpi)
+	case FormatPNG1Bit:
+		return encodePNG1Bit(w, img, dpi)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	case FormatJPEG:
+		quality := q.JPEGQuality
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+
+		options := &jpeg.Options{Quality: quality}
+		if dpi <= 0 {
+			return jpeg.Encode(w, img, options)
+		}
+
+		var b bytes.Buffer
+		if err := jpeg.Encode(&b, img, options); err != nil {
+			return err
+		}
+		return writeJPEGWithDPI(w, b.Bytes(), dpi)
+	case FormatBMP:
+		if dpi <= 0 {
+			return bmp.Encode(w, img)
+		}
+
+		var b bytes.Buffer
+		if err := bmp.Encode(&b, img); err != nil {
+			return err
+		}
+		return writeBMPWithDPI(w, b.Bytes(), dpi)
+	case FormatPBM:
+		return encodePBM(w, img)
+	case FormatPGM:
+		return encodePGM(w, img)
+	}
+
+	return fmt.Errorf("unknown image format %s", format)
+}
+
+// flattenImage returns img composited over an opaque white background, for
+// image formats without an alpha channel. Opaque images are returned as is.
+func flattenImage(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+
+	b := img.Bounds()
+	flat := image.NewRGBA(b)
+	draw.Draw(flat, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, b, img, b.Min, draw.Over)
+
+	return flat
+}
+
+// flattenGIFImage returns img with any translucent colours composited over
+// white. GIF images support fully transparent pixels only.
+//
+// The colours of paletted images are flattened in the palette, other images
+// are flattened entirely.
+func flattenGIFImage(img image.Image) image.Image {
+	p, ok := img.(image.PalettedImage)
+	if !ok {
+		return flattenImage(img)
+	}
+
+	palette, ok := p.ColorModel().(color.Palette)
+	if !ok {
+		return flattenImage(img)
+	}
+
+	var flatPalette color.Palette
+	for i, c := range palette {
+		if _, _, _, a := c.RGBA(); a == 0 || a == 0xffff {
+			continue
+		}
+
+		if flatPalette == nil {
+			flatPalette = make(color.Palette, len(palette))
+			copy(flatPalette, palette)
+		}
+
+		flatPalette[i] = flattenColor(c)
+	}
+
+	if flatPalette == nil {
+		return img
+	}
+
+	b := img.Bounds()
+	flat := image.NewPaletted(b, flatPalette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			flat.SetColorIndex(x, y, p.ColorIndexAt(x, y))
+		}
+	}
+
+	return flat
+}
+
+// flattenColor returns c composited over white.
+func flattenColor(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+
+	return color.RGBA64{
+		R: uint16(r + 0xffff - a),
+		G: uint16(g + 0xffff - a),
+		B: uint16(b + 0xffff - a),
+		A: 0xffff,
+	}
+}
+
+// isDark returns true if c is closer to black than to white.
+func isDark(c color.Color) bool {
+	return color.GrayModel.Convert(c).(color.Gray).Y < 0x80
+}
+
+// encodePBM writes img as a binary Netpbm bitmap. Dark pixels are written as
+// set bits.
+func encodePBM(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "P4\n%d %d\n", b.Dx(), b.Dy())
+
+	row := make([]byte, (b.Dx()+7)/8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if isDark(img.At(x, y)) {
+				i := x - b.Min.X
+				row[i/8] |= 0x80 >> uint(i%8)
+			}
+		}
+
+		out.Write(row)
+	}
+
+	return out.Flush()
+}
+
+// encodePGM writes img as a binary 8-bit Netpbm graymap.
+func encodePGM(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "P5\n%d %d\n255\n", b.Dx(), b.Dy())
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.WriteByte(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+		}
+	}
+
+	return out.Flush()
+}
+
+// pngSignature is the fixed 8 byte header of every PNG file.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// writePNGChunk writes a single PNG chunk of type typ.
+func writePNGChunk(w io.Writer, typ string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], crc.Sum32())
+
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodePNG1Bit writes img as a 1-bit grayscale PNG. Dark pixels are written
+// as black, all others as white. A positive dpi is written as a pHYs chunk.
+//
+// The standard library only writes 1-bit PNGs for paletted images, so the
+// image is written chunk by chunk here.
+func encodePNG1Bit(w io.Writer, img image.Image, dpi int) error {
+	b := img.Bounds()
+
+	if _, err := io.WriteString(w, pngSignature); err != nil {
+		return err
+	}
+
+	// Width, height, bit depth 1, colour type 0 (grayscale), default
+	// compression and filter, no interlace.
+	var ihdr [13]byte
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(b.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(b.Dy()))
+	ihdr[8] = 1
+	if err := writePNGChunk(w, "IHDR", ihdr[:]); err != nil {
+		return err
+	}
+
+	if dpi > 0 {
+		if err := writePNGChunk(w, "pHYs", pngPHYs(dpi)); err != nil {
+			return err
+		}
+	}
+
+	var idat bytes.Buffer
+	z, err := zlib.NewWriterLevel(&idat, zlib.BestCompression)
+	if err != nil {
+		return err
+	}
+
+	// Each row is preceded by its filter type, 0 (none).
+	row := make([]byte, 1+(b.Dx()+7)/8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !isDark(img.At(x, y)) {
+				i := x - b.Min.X
+				row[1+i/8] |= 0x80 >> uint(i%8)
+			}
+		}
+
+		if _, err := z.Write(row); err != nil {
+			return err
+		}
+	}
+
+	if err := z.Close(); err != nil {
+		return err
+	}
+
+	if err := writePNGChunk(w, "IDAT", idat.Bytes()); err != nil {
+		return err
+	}
+
+	return writePNGChunk(w, "IEND", nil)
+}
@@ -0,0 +1,196 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"testing"
+
+	_ "golang.org/x/image/bmp"
+)
+
+func TestEncodeFormats(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		format Format
+		name   string
+	}{
+		{FormatPNG, "png"},
+		{FormatPNG1Bit, "png"},
+		{FormatGIF, "gif"},
+		{FormatJPEG, "jpeg"},
+		{FormatBMP, "bmp"},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := q.Encode(&b, test.format, 100); err != nil {
+			t.Errorf("%s: got error %s, expected success", test.format, err)
+			continue
+		}
+
+		img, name, err := image.Decode(&b)
+		if err != nil {
+			t.Errorf("%s: decode failed: %s", test.format, err)
+			continue
+		}
+
+		if name != test.name {
+			t.Errorf("%s: decoded as %s, expected %s", test.format, name, test.name)
+		}
+
+		if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 100 {
+			t.Errorf("%s: got size %v, expected 100x100", test.format, img.Bounds())
+		}
+	}
+}
+
+func TestEncodePNG1Bit(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var b bytes.Buffer
+	if err := q.Encode(&b, FormatPNG1Bit, 250); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	paletted, err := q.PNG(250)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if b.Len() >= len(paletted) {
+		t.Errorf("1-bit PNG is %d bytes, expected less than %d", b.Len(), len(paletted))
+	}
+
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, ok := img.(*image.Gray); !ok {
+		t.Errorf("1-bit PNG decoded as %T, expected *image.Gray", img)
+	}
+
+	expected := q.Image(250)
+	for y := 0; y < 250; y++ {
+		for x := 0; x < 250; x++ {
+			if isDark(img.At(x, y)) != isDark(expected.At(x, y)) {
+				t.Fatalf("pixel (%d,%d) differs", x, y)
+			}
+		}
+	}
+}
+
+func TestEncodeNetpbm(t *testing.T) {
+	q, err := New("https://example.org", Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	realSize := len(q.Bitmap())
+
+	var b bytes.Buffer
+	if err := q.Encode(&b, FormatPBM, -1); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	header := fmt.Sprintf("P4\n%d %d\n", realSize, realSize)
+	rowBytes := (realSize + 7) / 8
+	if !bytes.HasPrefix(b.Bytes(), []byte(header)) {
+		t.Errorf("PBM header got %q, expected %q", b.Bytes()[:len(header)], header)
+	} else if b.Len() != len(header)+rowBytes*realSize {
+		t.Errorf("PBM got %d bytes, expected %d", b.Len(), len(header)+rowBytes*realSize)
+	}
+
+	b.Reset()
+	if err := q.Encode(&b, FormatPGM, -1); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	header = fmt.Sprintf("P5\n%d %d\n255\n", realSize, realSize)
+	if !bytes.HasPrefix(b.Bytes(), []byte(header)) {
+		t.Errorf("PGM header got %q, expected %q", b.Bytes()[:len(header)], header)
+	} else if b.Len() != len(header)+realSize*realSize {
+		t.Errorf("PGM got %d bytes, expected %d", b.Len(), len(header)+realSize*realSize)
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		format   Format
+		ok       bool
+	}{
+		{"qr.png", FormatPNG, true},
+		{"qr.GIF", FormatGIF, true},
+		{"qr.jpg", FormatJPEG, true},
+		{"qr.jpeg", FormatJPEG, true},
+		{"dir.v2/qr.bmp", FormatBMP, true},
+		{"qr.pbm", FormatPBM, true},
+		{"qr.pgm", FormatPGM, true},
+		{"qr.svg", FormatSVG, true},
+		{"qr", FormatPNG, false},
+		{"qr.tiff", FormatPNG, false},
+	}
+
+	for _, test := range tests {
+		format, err := FormatFromFilename(test.filename)
+
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got error %v, expected ok=%t", test.filename, err, test.ok)
+		} else if test.ok && format != test.format {
+			t.Errorf("%s: got %s, expected %s", test.filename, format, test.format)
+		}
+	}
+}
+
+func TestIsDark(t *testing.T) {
+	if !isDark(color.Black) || isDark(color.White) {
+		t.Error("isDark misclassifies black or white")
+	}
+}
+
+func TestEncodeGIFTranslucent(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.BackgroundColor = color.Transparent
+	q.PixelColor = color.NRGBA{0, 0, 0, 0x80}
+
+	var b bytes.Buffer
+	if err := q.Encode(&b, FormatGIF, 100); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img, _, err := image.Decode(&b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	palette := img.ColorModel().(color.Palette)
+	for _, c := range palette {
+		if _, _, _, a := c.RGBA(); a != 0 && a != 0xffff {
+			t.Errorf("GIF palette has translucent colour %v", c)
+		}
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("quiet zone alpha got %d, expected 0", a)
+	}
+}
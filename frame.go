@@ -0,0 +1,371 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// FrameShape is the shape of a frame drawn around a QR Code.
+type FrameShape int
+
+const (
+	// FrameBorder is a square border, extended to hold the caption.
+	FrameBorder FrameShape = iota
+
+	// FrameRounded is a border with rounded corners, extended to hold the
+	// caption.
+	FrameRounded
+
+	// FrameBubble is a rounded border, with the caption in a separate speech
+	// bubble pointing at the QR Code.
+	FrameBubble
+)
+
+// CaptionPosition is the position of a frame's caption.
+type CaptionPosition int
+
+const (
+	// CaptionBelow places the caption below the QR Code.
+	CaptionBelow CaptionPosition = iota
+
+	// CaptionAbove places the caption above the QR Code.
+	CaptionAbove
+)
+
+// Frame is a frame drawn around a QR Code, with an optional caption, e.g.
+// "SCAN ME" or a short URL.
+//
+// Lengths are in modules, so the frame scales with the QR Code.
+type Frame struct {
+	// Shape of the frame.
+	Shape FrameShape
+
+	// Colour of the frame. Defaults to the QRCode's BoxColor.
+	Color color.Color
+
+	// Width of the frame's border. Defaults to 1 module.
+	Thickness float64
+
+	// Caption text, drawn on the frame. No caption is drawn if empty.
+	Caption string
+
+	// Position of the caption.
+	CaptionPosition CaptionPosition
+
+	// Colour of the caption text. Defaults to the QRCode's BackgroundColor.
+	CaptionColor color.Color
+
+	// Font of the caption, e.g. parsed from a TrueType file with sfnt.Parse.
+	// Defaults to an embedded 7x13 pixel bitmap font.
+	Font *sfnt.Font
+
+	// Font size (height of an em) of the caption. Defaults to 1/8 of the
+	// width of the QR Code. The caption is made smaller if necessary, to fit
+	// the width of the QR Code.
+	FontSize float64
+}
+
+const (
+	// Height of a caption's band or bubble, relative to the font size.
+	frameCaptionLineHeight = 1.6
+
+	// Height and half width of a speech bubble's tail, relative to the font
+	// size.
+	frameTailSize = 0.6
+)
+
+// frameLayout is a Frame laid out around the QR Code, in a coordinate system
+// one unit per module with the origin at the top left of the canvas.
+type frameLayout struct {
+	// Size of the canvas.
+	width, height float64
+
+	// Position of the top left of the QR Code (including its quiet zone).
+	x, y float64
+
+	// The frame, and caption text.
+	frame, caption Path
+
+	frameColor, captionColor color.Color
+}
+
+// frameLayout lays out f around the encoded QR Code. A nil f is no frame.
+func (q *QRCode) frameLayout(f *Frame) (*frameLayout, error) {
+	n := float64(q.symbol.size)
+
+	if f == nil {
+		return &frameLayout{width: n, height: n}, nil
+	}
+
+	l := &frameLayout{
+		frameColor:   q.BoxColor,
+		captionColor: q.BackgroundColor,
+	}
+	if f.Color != nil {
+		l.frameColor = f.Color
+	}
+	if f.CaptionColor != nil {
+		l.captionColor = f.CaptionColor
+	}
+
+	t := f.Thickness
+	if t <= 0 {
+		t = 1
+	}
+
+	// The caption, sized to fit.
+	var text *textOutline
+	var fontSize, band float64
+
+	if f.Caption != "" {
+		var err error
+		if text, err = newTextOutline(f.Caption, f.Font); err != nil {
+			return nil, err
+		}
+
+		fontSize = f.FontSize
+		if fontSize <= 0 {
+			fontSize = n / 8
+		}
+		if text.width*fontSize > n {
+			fontSize = n / text.width
+		}
+
+		band = fontSize * frameCaptionLineHeight
+	}
+
+	// The border's corners are rounded, but the inner corners no more than the
+	// quiet zone allows.
+	w := n + 2*t
+	innerRadius := math.Min(2, float64(q.symbol.quietZoneSize))
+	outerRadius := innerRadius + t
+	if f.Shape == FrameBorder {
+		innerRadius, outerRadius = 0, 0
+	}
+
+	l.width = w
+	l.x, l.y = t, t
+
+	above := f.CaptionPosition == CaptionAbove
+
+	// Vertical centre of the caption.
+	var captionY float64
+
+	switch {
+	case f.Shape == FrameBubble && text != nil:
+		tail := fontSize * frameTailSize
+
+		l.height = w + tail + band
+
+		ringY, bubbleY := 0.0, w+tail
+		tailBase, tailApex := w+tail, w
+		if above {
+			ringY, bubbleY = band+tail, 0
+			tailBase, tailApex = band, band+tail
+		}
+
+		l.y = ringY + t
+		l.frame.AddRoundedRect(0, ringY, w, w, uniformRadii(outerRadius))
+
+		bubbleRadius := math.Min(band/2, outerRadius)
+		l.frame.AddRoundedRect(0, bubbleY, w, band, uniformRadii(bubbleRadius))
+
+		// The tail, clockwise.
+		cx := w / 2
+		if above {
+			l.frame.AddPolygon(cx+tail, tailBase, cx, tailApex, cx-tail, tailBase)
+		} else {
+			l.frame.AddPolygon(cx-tail, tailBase, cx, tailApex, cx+tail, tailBase)
+		}
+
+		captionY = bubbleY + band/2
+	default:
+		l.height = w + band
+		if above {
+			l.y += band
+		}
+
+		l.frame.AddRoundedRect(0, 0, w, l.height, uniformRadii(outerRadius))
+
+		// The caption is centred in the band, including the border.
+		captionY = w + (band-t)/2
+		if above {
+			captionY = (t + band) / 2
+		}
+	}
+
+	// The hole for the QR Code.
+	var hole Path
+	hole.AddRoundedRect(l.x, l.y, n, n, uniformRadii(innerRadius))
+	l.frame.Append(hole.reversed())
+
+	if text != nil {
+		x := (w - text.width*fontSize) / 2
+		baseline := captionY + (text.ascent-text.descent)*fontSize/2
+
+		l.caption = *text.path.transformed(fontSize, x, baseline)
+	}
+
+	return l, nil
+}
+
+// uniformRadii returns the corner radii of a rectangle with all corners
+// rounded by r.
+func uniformRadii(r float64) [4]float64 {
+	return [4]float64{r, r, r, r}
+}
+
+// FrameImage returns img, an image of the QR Code (e.g. from Image,
+// BeautifyImage or Halftone), with the frame f drawn around it.
+//
+// The frame is scaled to the size of the modules in img. Outside the frame,
+// the image is transparent.
+func (q *QRCode) FrameImage(img image.Image, f *Frame) (image.Image, error) {
+	q.encode()
+
+	l, err := q.frameLayout(f)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	scale := float64(b.Dx()) / float64(q.symbol.size)
+
+	result := image.NewRGBA(image.Rect(0, 0,
+		int(math.Round(l.width*scale)), int(math.Round(l.height*scale))))
+
+	l.frame.transformed(scale, 0, 0).fill(result, l.frameColor)
+
+	at := image.Pt(int(math.Round(l.x*scale)), int(math.Round(l.y*scale)))
+	draw.Draw(result, b.Sub(b.Min).Add(at), img, b.Min, draw.Over)
+
+	l.caption.transformed(scale, 0, 0).fill(result, l.captionColor)
+
+	return result, nil
+}
+
+// FramedSVG returns the QR Code as an SVG image, with the frame f drawn around
+// it.
+//
+// size is the image width in pixels. Negative values for size set the size of
+// each module instead: See the documentation for Image().
+func (q *QRCode) FramedSVG(size int, f *Frame) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := q.WriteFramedSVG(&b, size, f); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteFramedSVG writes the QR Code as an SVG image to w, with the frame f
+// drawn around it. See FramedSVG for details.
+func (q *QRCode) WriteFramedSVG(w io.Writer, size int, f *Frame) error {
+	q.encode()
+
+	realSize := q.symbol.size
+
+	l, err := q.frameLayout(f)
+	if err != nil {
+		return err
+	}
+
+	// Pixels per module.
+	scale := float64(size) / l.width
+	if size < 0 {
+		scale = float64(-size)
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%s" height="%s" viewBox="0 0 %s %s">
+`, strconv.Itoa(int(math.Round(l.width*scale))), strconv.Itoa(int(math.Round(l.height*scale))),
+		formatCoordinate(l.width), formatCoordinate(l.height))
+
+	writeSVGPath(&b, &l.frame, svgFill(l.frameColor), false)
+
+	fmt.Fprintf(&b, "<g transform=\"translate(%s %s)\">\n", formatCoordinate(l.x), formatCoordinate(l.y))
+	fmt.Fprintf(&b, "<rect width=\"%d\" height=\"%d\" %s/>\n", realSize, realSize, svgFill(q.BackgroundColor))
+	q.writeSVGSymbol(&b)
+	b.WriteString("</g>\n")
+
+	writeSVGPath(&b, &l.caption, svgFill(l.captionColor), false)
+
+	b.WriteString("</svg>\n")
+
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// FramedPDF returns the QR Code as a single page PDF document, with the frame
+// f drawn around it. f may be nil, for no frame.
+//
+// The page is length wide, in unit. The QR Code is drawn in vector form, with
+// ModuleShape and the finder and alignment pattern styles. Gradients are drawn
+// with their midpoint colour, and translucent colours are composited over
+// white.
+func (q *QRCode) FramedPDF(length float64, unit Unit, f *Frame) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := q.WriteFramedPDF(&b, length, unit, f); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteFramedPDF writes the QR Code as a PDF document to w, with the frame f
+// drawn around it. See FramedPDF for details.
+func (q *QRCode) WriteFramedPDF(w io.Writer, length float64, unit Unit, f *Frame) error {
+	q.encode()
+
+	realSize := float64(q.symbol.size)
+
+	l, err := q.frameLayout(f)
+	if err != nil {
+		return err
+	}
+
+	// Points per module.
+	scale := unit.inches(length) * pointsPerInch / l.width
+	width, height := l.width*scale, l.height*scale
+
+	var c bytes.Buffer
+
+	// Flip the y axis, and draw in modules.
+	fmt.Fprintf(&c, "%s 0 0 %s 0 %s cm\n",
+		formatCoordinate(scale), formatCoordinate(-scale), formatCoordinate(height))
+
+	writePDFPath(&c, &l.frame, l.frameColor)
+
+	fmt.Fprintf(&c, "q 1 0 0 1 %s %s cm\n", formatCoordinate(l.x), formatCoordinate(l.y))
+
+	var background Path
+	background.AddRect(0, 0, realSize, realSize)
+	writePDFPath(&c, &background, q.BackgroundColor)
+
+	for _, layer := range q.symbolLayers() {
+		writePDFPath(&c, layer.path, layer.color)
+	}
+	c.WriteString("Q\n")
+
+	writePDFPath(&c, &l.caption, l.captionColor)
+
+	return writePDF(w, width, height, c.Bytes())
+}
@@ -0,0 +1,221 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestTextOutline(t *testing.T) {
+	text, err := newTextOutline("SCAN ME", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Seven 7px wide characters, with a line height of 13px.
+	if math.Abs(text.width-49.0/13) > 1e-9 || math.Abs(text.ascent+text.descent-1) > 1e-9 {
+		t.Errorf("bitmap font got width %f, ascent %f, descent %f", text.width, text.ascent, text.descent)
+	}
+	if text.path.Empty() {
+		t.Error("bitmap font got empty path")
+	}
+
+	fnt, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	text, err = newTextOutline("SCAN ME", fnt)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if text.width < 3 || text.width > 6 || text.ascent <= 0.5 || text.descent <= 0 || text.path.Empty() {
+		t.Errorf("TrueType font got width %f, ascent %f, descent %f", text.width, text.ascent, text.descent)
+	}
+
+	if text, _ := newTextOutline("", fnt); !text.path.Empty() || text.width != 0 {
+		t.Errorf("empty text got width %f", text.width)
+	}
+}
+
+func TestFrameLayout(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.encode()
+
+	// 33 modules, plus a 1 module border each side. The 4 module font size is
+	// a 6.4 module band.
+	tests := []struct {
+		frame         *Frame
+		width, height float64
+		x, y          float64
+	}{
+		{nil, 33, 33, 0, 0},
+		{&Frame{}, 35, 35, 1, 1},
+		{&Frame{Thickness: 2}, 37, 37, 2, 2},
+		{&Frame{Caption: "SCAN ME", FontSize: 4}, 35, 41.4, 1, 1},
+		{&Frame{Caption: "SCAN ME", FontSize: 4, CaptionPosition: CaptionAbove}, 35, 41.4, 1, 7.4},
+		{&Frame{Shape: FrameBubble, Caption: "SCAN ME", FontSize: 4}, 35, 43.8, 1, 1},
+		{&Frame{Shape: FrameBubble, Caption: "SCAN ME", FontSize: 4, CaptionPosition: CaptionAbove}, 35, 43.8, 1, 9.8},
+	}
+
+	for i, test := range tests {
+		l, err := q.frameLayout(test.frame)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		near := func(a, b float64) bool {
+			return math.Abs(a-b) < 1e-9
+		}
+
+		if !near(l.width, test.width) || !near(l.height, test.height) || !near(l.x, test.x) || !near(l.y, test.y) {
+			t.Errorf("test %d got %fx%f at (%f,%f), expected %fx%f at (%f,%f)", i,
+				l.width, l.height, l.x, l.y, test.width, test.height, test.x, test.y)
+		}
+	}
+
+	// A long caption is made smaller, to fit the width of the QR Code.
+	l, err := q.frameLayout(&Frame{Caption: "https://example.org/a/long/caption", FontSize: 4})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if l.height >= 41.4 {
+		t.Errorf("long caption got height %f, expected a smaller font", l.height)
+	}
+}
+
+func TestFrameImage(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	red := color.RGBA{0xc0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	img := q.Image(-10)
+	framed, err := q.FrameImage(img, &Frame{Shape: FrameRounded, Color: red, Caption: "SCAN ME", FontSize: 4})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	at := func(x, y int) color.RGBA {
+		return color.RGBAModel.Convert(framed.At(x, y)).(color.RGBA)
+	}
+
+	// 35x41.4 modules at 10px.
+	if b := framed.Bounds(); b.Dx() != 350 || b.Dy() != 414 {
+		t.Fatalf("got bounds %v, expected 350x414", b)
+	}
+
+	// Transparent outside the rounded corner, then the frame, then the QR Code
+	// drawn unchanged.
+	if c := at(0, 0); c.A != 0 {
+		t.Errorf("corner got %v, expected transparent", c)
+	}
+	if c := at(5, 150); c != red {
+		t.Errorf("frame got %v, expected %v", c, red)
+	}
+	for _, p := range [][2]int{{0, 0}, {45, 45}, {75, 75}, {145, 200}} {
+		expected := color.RGBAModel.Convert(img.At(p[0], p[1])).(color.RGBA)
+		if c := at(p[0]+10, p[1]+10); c != expected {
+			t.Errorf("QR Code pixel %v got %v, expected %v", p, c, expected)
+		}
+	}
+
+	// The caption is drawn in the band below.
+	var numWhite int
+	for y := 340; y < 414; y++ {
+		for x := 0; x < 350; x++ {
+			if at(x, y) == white {
+				numWhite++
+			}
+		}
+	}
+
+	if numWhite < 500 {
+		t.Errorf("got %d caption pixels, expected the caption", numWhite)
+	}
+}
+
+func TestFramedSVG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svg, err := q.FramedSVG(350, &Frame{Caption: "SCAN ME", FontSize: 4})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, expected := range []string{
+		`width="350" height="414" viewBox="0 0 35 41.4"`,
+		`<g transform="translate(1 1)">`,
+		`<rect width="33" height="33" fill="#ffffff"/>`,
+		`fill="#ffffff"/>` + "\n</svg>",
+	} {
+		if !strings.Contains(string(svg), expected) {
+			t.Errorf("SVG missing %q", expected)
+		}
+	}
+}
+
+func TestFramedPDF(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	pdf, err := q.FramedPDF(35, Millimetre, &Frame{Caption: "SCAN ME", FontSize: 4})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Error("PDF header or trailer missing")
+	}
+
+	// 35mm wide, 41.4mm high.
+	if !bytes.Contains(pdf, []byte("/MediaBox [0 0 99.213 117.354]")) {
+		t.Errorf("PDF missing expected MediaBox")
+	}
+
+	// The cross-reference table points to each object.
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if startxref == nil {
+		t.Fatal("PDF missing startxref")
+	}
+
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Errorf("startxref %d doesn't point to the xref table", xref)
+	}
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1)
+	if len(offsets) != 4 {
+		t.Fatalf("got %d objects, expected 4", len(offsets))
+	}
+
+	for i, o := range offsets {
+		offset, _ := strconv.Atoi(string(o[1]))
+		if !bytes.HasPrefix(pdf[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")) {
+			t.Errorf("object %d offset %d is wrong", i+1, offset)
+		}
+	}
+}
@@ -1,5 +1,8 @@
 module github.com/skip2/go-qrcode
 
-go 1.13
+go 1.16
 
-require github.com/disintegration/imaging v1.6.2
+require (
+	github.com/disintegration/imaging v1.6.2
+	golang.org/x/image v0.0.0-20191009234506-e7c1f5e7dbb8
+)
@@ -0,0 +1,349 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// GradientKind is the kind of a Gradient.
+type GradientKind int
+
+const (
+	// LinearGradient varies the colour along a straight line across the
+	// symbol, at Gradient.Angle.
+	LinearGradient GradientKind = iota
+
+	// RadialGradient varies the colour from the centre of the symbol outwards
+	// to its corners.
+	RadialGradient
+)
+
+// GradientStop is a colour at a position along a Gradient.
+type GradientStop struct {
+	// Position along the gradient, from 0 (start) to 1 (end).
+	Offset float64
+
+	Color color.Color
+}
+
+// Gradient is a foreground fill which varies in colour across the symbol.
+//
+// The gradient spans the symbol, excluding the quiet zone. Stops must be in
+// increasing Offset order. Positions before the first stop or after the last
+// are the colour of that stop.
+type Gradient struct {
+	Kind GradientKind
+
+	// Direction of a LinearGradient, in degrees clockwise from left-to-right.
+	// For example, 90 is top-to-bottom and 45 is top left to bottom right.
+	Angle float64
+
+	Stops []GradientStop
+}
+
+// colorAt returns the (premultiplied) colour at position t along g.
+func (g *Gradient) colorAt(t float64) color.RGBA64 {
+	return g.interpolate(g.stopColors(), t)
+}
+
+// stopColors returns the (premultiplied) colours of g's stops.
+func (g *Gradient) stopColors() [][4]float64 {
+	colors := make([][4]float64, len(g.Stops))
+	for i, s := range g.Stops {
+		r, g, b, a := s.Color.RGBA()
+		colors[i] = [4]float64{float64(r), float64(g), float64(b), float64(a)}
+	}
+
+	return colors
+}
+
+// interpolate returns the colour at position t along g, given the colours of
+// its stops.
+func (g *Gradient) interpolate(colors [][4]float64, t float64) color.RGBA64 {
+	if len(g.Stops) == 0 {
+		return color.RGBA64{}
+	}
+
+	c := colors[len(colors)-1]
+
+	for i, s := range g.Stops {
+		if t > s.Offset {
+			continue
+		}
+
+		c = colors[i]
+
+		if i > 0 && s.Offset > g.Stops[i-1].Offset {
+			prev := g.Stops[i-1]
+			w := (t - prev.Offset) / (s.Offset - prev.Offset)
+
+			p := colors[i-1]
+			for k := range c {
+				c[k] = p[k] + w*(c[k]-p[k])
+			}
+		}
+
+		break
+	}
+
+	// The channels are never negative, so adding 0.5 and truncating rounds
+	// them to the nearest integer, faster than math.Round.
+	return color.RGBA64{
+		R: uint16(c[0] + 0.5),
+		G: uint16(c[1] + 0.5),
+		B: uint16(c[2] + 0.5),
+		A: uint16(c[3] + 0.5),
+	}
+}
+
+// geometry returns the gradient line (for LinearGradient), or the centre and
+// radius (for RadialGradient), for a gradient spanning the w*w square at
+// (x, y).
+//
+// The linear gradient line passes through the centre of the square, and is
+// long enough for the corners to be at the start and end of the gradient.
+func (g *Gradient) geometry(x, y, w float64) (x1, y1, x2, y2 float64) {
+	cx, cy := x+w/2, y+w/2
+
+	if g.Kind == RadialGradient {
+		return cx, cy, w / math.Sqrt2, 0
+	}
+
+	sin, cos := math.Sincos(g.Angle * math.Pi / 180)
+	half := (math.Abs(sin) + math.Abs(cos)) * w / 2
+
+	return cx - cos*half, cy - sin*half, cx + cos*half, cy + sin*half
+}
+
+// gradientImage is an image of a Gradient spanning a square area. It is used
+// as the source image when drawing modules.
+type gradientImage struct {
+	g      *Gradient
+	bounds image.Rectangle
+
+	// Colours of the gradient's stops.
+	colors [][4]float64
+
+	// Gradient line, or centre and radius.
+	x1, y1, x2, y2 float64
+}
+
+// newGradientImage returns g spanning the w*w square at (x, y), within an
+// image with the given bounds.
+func newGradientImage(g *Gradient, bounds image.Rectangle, x, y, w float64) *gradientImage {
+	i := &gradientImage{g: g, bounds: bounds, colors: g.stopColors()}
+	i.x1, i.y1, i.x2, i.y2 = g.geometry(x, y, w)
+
+	return i
+}
+
+func (i *gradientImage) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (i *gradientImage) Bounds() image.Rectangle {
+	return i.bounds
+}
+
+func (i *gradientImage) At(x, y int) color.Color {
+	return i.rgba64At(x, y)
+}
+
+// rgba64At returns the colour at (x, y), without allocating.
+func (i *gradientImage) rgba64At(x, y int) color.RGBA64 {
+	// Sample at the pixel centre.
+	px, py := float64(x)+0.5, float64(y)+0.5
+
+	var t float64
+	if i.g.Kind == RadialGradient {
+		t = math.Hypot(px-i.x1, py-i.y1) / i.x2
+	} else {
+		dx, dy := i.x2-i.x1, i.y2-i.y1
+		t = ((px-i.x1)*dx + (py-i.y1)*dy) / (dx*dx + dy*dy)
+	}
+
+	return i.g.interpolate(i.colors, t)
+}
+
+// paint returns the source image to draw modules with: c, or g if set. The
+// gradient spans the symbol (excluding the quiet zone) in a size*size image.
+func (q *QRCode) paint(c color.Color, g *Gradient, size int) image.Image {
+	if g == nil {
+		return image.NewUniform(c)
+	}
+
+	realSize := float64(q.symbol.size)
+	border := float64(q.symbol.borderSize())
+	pixelsPerModule := float64(size) / realSize
+
+	return newGradientImage(g, image.Rect(0, 0, size, size),
+		border*pixelsPerModule, border*pixelsPerModule, (realSize-2*border)*pixelsPerModule)
+}
+
+// writeSVGGradient writes g as an SVG gradient element with the given id,
+// spanning the symbol (excluding the quiet zone) in module coordinates.
+func (q *QRCode) writeSVGGradient(b *bytes.Buffer, id string, g *Gradient) {
+	border := float64(q.symbol.borderSize())
+	x1, y1, x2, y2 := g.geometry(border, border, float64(q.symbol.size)-2*border)
+
+	f := formatCoordinate
+	if g.Kind == RadialGradient {
+		fmt.Fprintf(b, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s">`,
+			id, f(x1), f(y1), f(x2))
+	} else {
+		fmt.Fprintf(b, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s">`,
+			id, f(x1), f(y1), f(x2), f(y2))
+	}
+	b.WriteString("\n")
+
+	for _, s := range g.Stops {
+		hex, opacity := svgColor(s.Color)
+
+		fmt.Fprintf(b, `<stop offset="%s" stop-color="%s"`, f(s.Offset), hex)
+		if opacity != 1 {
+			fmt.Fprintf(b, ` stop-opacity="%s"`, f(opacity))
+		}
+		b.WriteString("/>\n")
+	}
+
+	if g.Kind == RadialGradient {
+		b.WriteString("</radialGradient>\n")
+	} else {
+		b.WriteString("</linearGradient>\n")
+	}
+}
+
+// minContrastRatio is the minimum WCAG contrast ratio between the foreground
+// and background colours accepted by CheckContrast.
+const minContrastRatio = 3
+
+// CheckContrast returns an error if any foreground colour, including each
+// gradient stop, is too close to the background colour in luminance for the
+// QR Code to be reliably scanned.
+//
+// Colours are compared by their WCAG 2 contrast ratio, which must be at least
+// 3:1. Translucent colours are compared as drawn: The background over white,
+// and the foreground over the background.
+func (q *QRCode) CheckContrast() error {
+	background := flattenColor(q.BackgroundColor)
+
+	type namedColor struct {
+		name string
+		c    color.Color
+	}
+
+	var colors []namedColor
+	add := func(name string, c color.Color, gradientName string, g *Gradient) {
+		if g == nil {
+			colors = append(colors, namedColor{name, c})
+			return
+		}
+
+		for i, s := range g.Stops {
+			colors = append(colors, namedColor{fmt.Sprintf("%s stop %d", gradientName, i), s.Color})
+		}
+	}
+
+	add("PixelColor", q.PixelColor, "DataGradient", q.DataGradient)
+	add("BoxColor", q.BoxColor, "FinderGradient", q.FinderGradient)
+
+	for _, s := range []struct {
+		name  string
+		style *EyeStyle
+	}{
+		{"FinderStyle", q.FinderStyle},
+		{"AlignmentStyle", q.AlignmentStyle},
+	} {
+		if s.style == nil {
+			continue
+		}
+		if s.style.OuterColor != nil {
+			add(s.name+".OuterColor", s.style.OuterColor, "", nil)
+		}
+		if s.style.InnerColor != nil {
+			add(s.name+".InnerColor", s.style.InnerColor, "", nil)
+		}
+	}
+
+	var low []string
+	for _, c := range colors {
+		if ratio := contrastRatio(compositeColor(c.c, background), background); ratio < minContrastRatio {
+			low = append(low, fmt.Sprintf("%s (%.2f:1)", c.name, ratio))
+		}
+	}
+
+	if len(low) > 0 {
+		return fmt.Errorf("low contrast with the background colour: %s",
+			strings.Join(low, ", "))
+	}
+
+	return nil
+}
+
+// compositeColor returns c composited over the opaque colour background.
+func compositeColor(c, background color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	br, bg, bb, _ := background.RGBA()
+
+	return compositeRGBA64(r, g, b, a, br, bg, bb)
+}
+
+// compositeRGBA64 returns the premultiplied colour (r, g, b, a) composited
+// over the opaque colour (br, bg, bb).
+func compositeRGBA64(r, g, b, a, br, bg, bb uint32) color.RGBA64 {
+	return color.RGBA64{
+		R: uint16(r + br*(0xffff-a)/0xffff),
+		G: uint16(g + bg*(0xffff-a)/0xffff),
+		B: uint16(b + bb*(0xffff-a)/0xffff),
+		A: 0xffff,
+	}
+}
+
+// sourceColor returns the colour of src at (x, y). The module fills returned
+// by paint are read without allocating.
+func sourceColor(src image.Image, x, y int) (r, g, b, a uint32) {
+	switch src := src.(type) {
+	case *gradientImage:
+		c := src.rgba64At(x, y)
+		return uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+	case *image.Uniform:
+		return src.C.RGBA()
+	}
+
+	return src.At(x, y).RGBA()
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of c, from 0
+// (black) to 1 (white).
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+
+	linear := func(v uint32) float64 {
+		s := float64(v) / 0xffff
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
+
+// contrastRatio returns the WCAG 2 contrast ratio between two opaque colours,
+// from 1 (no contrast) to 21 (black and white).
+func contrastRatio(a, b color.Color) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+
+	return (la + 0.05) / (lb + 0.05)
+}
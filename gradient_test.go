@@ -0,0 +1,186 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestGradientColorAt(t *testing.T) {
+	g := &Gradient{
+		Stops: []GradientStop{
+			{0.25, color.RGBA{0xff, 0, 0, 0xff}},
+			{0.75, color.RGBA{0, 0, 0xff, 0xff}},
+		},
+	}
+
+	tests := []struct {
+		t        float64
+		expected color.RGBA64
+	}{
+		{-1, color.RGBA64{0xffff, 0, 0, 0xffff}},
+		{0.25, color.RGBA64{0xffff, 0, 0, 0xffff}},
+		{0.5, color.RGBA64{0x8000, 0, 0x8000, 0xffff}},
+		{0.75, color.RGBA64{0, 0, 0xffff, 0xffff}},
+		{2, color.RGBA64{0, 0, 0xffff, 0xffff}},
+	}
+
+	for _, test := range tests {
+		if got := g.colorAt(test.t); got != test.expected {
+			t.Errorf("colorAt(%v) got %v, expected %v", test.t, got, test.expected)
+		}
+	}
+}
+
+func TestGradientImage(t *testing.T) {
+	black := color.RGBA64{0, 0, 0, 0xffff}
+	white := color.RGBA64{0xffff, 0xffff, 0xffff, 0xffff}
+	stops := []GradientStop{{0, black}, {1, white}}
+
+	tests := []struct {
+		g      Gradient
+		at     [][2]int
+		bright []bool
+	}{
+		// Left to right.
+		{Gradient{Kind: LinearGradient, Angle: 0, Stops: stops},
+			[][2]int{{0, 50}, {99, 50}, {99, 0}}, []bool{false, true, true}},
+		// Top to bottom.
+		{Gradient{Kind: LinearGradient, Angle: 90, Stops: stops},
+			[][2]int{{50, 0}, {50, 99}, {0, 99}}, []bool{false, true, true}},
+		// Diagonal, top left to bottom right.
+		{Gradient{Kind: LinearGradient, Angle: 45, Stops: stops},
+			[][2]int{{0, 0}, {99, 99}, {0, 30}}, []bool{false, true, false}},
+		// Centre outwards.
+		{Gradient{Kind: RadialGradient, Stops: stops},
+			[][2]int{{50, 50}, {0, 0}, {99, 0}}, []bool{false, true, true}},
+	}
+
+	for i, test := range tests {
+		img := newGradientImage(&test.g, image.Rect(0, 0, 100, 100), 0, 0, 100)
+
+		for j, p := range test.at {
+			y := color.GrayModel.Convert(img.At(p[0], p[1])).(color.Gray).Y
+
+			if (y > 0xc0) != test.bright[j] || (y > 0x40 && y <= 0xc0) {
+				t.Errorf("gradient %d: pixel %v got gray %d, expected bright=%t",
+					i, p, y, test.bright[j])
+			}
+		}
+	}
+}
+
+func TestBeautifyImageGradient(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	q.FinderGradient = &Gradient{Angle: 0, Stops: []GradientStop{{0, red}, {1, blue}}}
+	q.DataGradient = &Gradient{Kind: RadialGradient, Stops: []GradientStop{{0, blue}, {1, blue}}}
+
+	// 33 modules at 10px, with a 4 module quiet zone. The symbol spans
+	// 40-290px.
+	img := q.BeautifyImage(-10)
+
+	r, _, b, _ := img.At(41, 45).RGBA()
+	if r < 0xf000 || b > 0x1000 {
+		t.Errorf("top left finder pattern got (%x, %x), expected red", r, b)
+	}
+
+	r, _, b, _ = img.At(288, 45).RGBA()
+	if r > 0x1000 || b < 0xf000 {
+		t.Errorf("top right finder pattern got (%x, %x), expected blue", r, b)
+	}
+
+	// The timing pattern is drawn with the data gradient.
+	if got := color.RGBAModel.Convert(img.At(125, 105)); got != blue {
+		t.Errorf("timing pattern got %v, expected %v", got, blue)
+	}
+}
+
+func TestSVGGradient(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.DataGradient = &Gradient{
+		Kind: RadialGradient,
+		Stops: []GradientStop{
+			{0, color.RGBA{0, 0, 0x80, 0xff}},
+			{1, color.NRGBA{0, 0, 0, 0x80}},
+		},
+	}
+	q.FinderGradient = &Gradient{Angle: 90, Stops: q.DataGradient.Stops}
+
+	b, err := q.SVG(-4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s := string(b)
+
+	for _, expected := range []string{
+		`<radialGradient id="qrcode-data-gradient" gradientUnits="userSpaceOnUse" cx="16.5" cy="16.5" r="17.678">`,
+		`<linearGradient id="qrcode-finder-gradient" gradientUnits="userSpaceOnUse" x1="16.5" y1="4" x2="16.5" y2="29">`,
+		`<stop offset="1" stop-color="#000000" stop-opacity="0.502"/>`,
+		`fill="url(#qrcode-data-gradient)"`,
+		`fill="url(#qrcode-finder-gradient)"`,
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("SVG does not contain %s", expected)
+		}
+	}
+
+	parseSVG(t, b)
+}
+
+func TestCheckContrast(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := q.CheckContrast(); err != nil {
+		t.Errorf("black on white got error %s", err)
+	}
+
+	q.DataGradient = &Gradient{
+		Stops: []GradientStop{
+			{0, color.Black},
+			{1, color.RGBA{0xe0, 0xe0, 0xe0, 0xff}},
+		},
+	}
+	q.FinderStyle = &EyeStyle{InnerColor: color.NRGBA{0, 0, 0, 0x20}}
+
+	err = q.CheckContrast()
+	if err == nil {
+		t.Fatal("light gray on white got no error")
+	}
+
+	for _, name := range []string{"DataGradient stop 1", "FinderStyle.InnerColor"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "stop 0") || strings.Contains(err.Error(), "BoxColor") {
+		t.Errorf("error %q mentions a high contrast colour", err)
+	}
+
+	// A dark background with light modules is fine.
+	q.BackgroundColor = color.Black
+	q.PixelColor = color.White
+	q.BoxColor = color.White
+	q.DataGradient = nil
+	q.FinderStyle = nil
+
+	if err := q.CheckContrast(); err != nil {
+		t.Errorf("white on black got error %s", err)
+	}
+}
@@ -0,0 +1,153 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/disintegration/imaging"
+)
+
+// halftoneSubModules is the number of sub-pixels across each module of a
+// halftone image.
+const halftoneSubModules = 3
+
+// Halftone returns the QR Code as a halftone image of picture.
+//
+// Each module is split into 3x3 sub-pixels. The centre sub-pixel carries the
+// module's value, which is what a QR Code reader samples. The surrounding
+// eight sub-pixels follow a dithered (black and white) copy of picture, which
+// is cropped to fill the symbol. So the image looks like the picture, but
+// decodes as a normal QR Code.
+//
+// The finder, alignment and timing patterns, the format and version
+// information, and the quiet zone are drawn as solid modules.
+//
+// size is both the image width and height in pixels. Each sub-pixel is drawn
+// the same whole number of pixels wide, with any leftover pixels added to the
+// margin. Negative values for size set the size of each sub-pixel instead:
+// e.g. size=-2 draws each module 6x6 pixels.
+func (q *QRCode) Halftone(picture image.Image, size int) image.Image {
+	// Build QR code.
+	q.encode()
+
+	realSize := q.symbol.size
+	subSize := realSize * halftoneSubModules
+
+	if size < 0 {
+		size = size * -1 * subSize
+	}
+
+	if size < subSize {
+		size = subSize
+	}
+
+	dots := q.halftoneDots(picture)
+
+	p := color.Palette([]color.Color{q.BackgroundColor, q.BoxColor, q.PixelColor})
+	img := image.NewPaletted(image.Rect(0, 0, size, size), p)
+
+	boxClr := uint8(img.Palette.Index(q.BoxColor))
+	fgClr := uint8(img.Palette.Index(q.PixelColor))
+
+	layout := newPixelLayout(size, subSize, ScaleInteger)
+
+	for y := 0; y < size; y++ {
+		y2 := layout.module[y]
+		if y2 < 0 {
+			continue
+		}
+
+		for x := 0; x < size; x++ {
+			x2 := layout.module[x]
+			if x2 < 0 || !dots[y2][x2] {
+				continue
+			}
+
+			clr := fgClr
+			if q.symbol.finderPatternModule.get(x2/halftoneSubModules, y2/halftoneSubModules) {
+				clr = boxClr
+			}
+
+			img.Pix[img.PixOffset(x, y)] = clr
+		}
+	}
+
+	return img
+}
+
+// halftoneDots returns the value of each sub-pixel of the halftone image of
+// picture.
+//
+// The picture is dithered with Floyd-Steinberg error diffusion. The error at
+// the centre sub-pixels, fixed by the data modules, is diffused too, so the
+// picture's tones are kept on average around them.
+func (q *QRCode) halftoneDots(picture image.Image) [][]bool {
+	m := q.symbol
+	n := halftoneSubModules
+	subSize := m.size * n
+
+	// The picture covers the symbol, excluding the quiet zone.
+	border := m.quietZoneSize * n
+	fitted := imaging.Fill(picture, m.symbolSize*n, m.symbolSize*n, imaging.Center, imaging.Lanczos)
+
+	// Desired darkness of each sub-pixel, from 0 (light) to 1 (dark).
+	// Transparent areas of the picture are light.
+	darkness := make([][]float64, subSize)
+	for y := range darkness {
+		darkness[y] = make([]float64, subSize)
+	}
+
+	for y := 0; y < m.symbolSize*n; y++ {
+		for x := 0; x < m.symbolSize*n; x++ {
+			gray := color.GrayModel.Convert(flattenColor(fitted.At(x, y))).(color.Gray)
+
+			darkness[y+border][x+border] = 1 - float64(gray.Y)/0xff
+		}
+	}
+
+	dots := make([][]bool, subSize)
+	for y := range dots {
+		dots[y] = make([]bool, subSize)
+	}
+
+	for y := 0; y < subSize; y++ {
+		for x := 0; x < subSize; x++ {
+			mx, my := x/n, y/n
+
+			// Solid modules don't take part in the dithering.
+			if !m.inSymbol(mx, my) || m.functionModule.get(mx, my) {
+				dots[y][x] = m.module.get(mx, my)
+				continue
+			}
+
+			v := darkness[y][x] >= 0.5
+			if x%n == n/2 && y%n == n/2 {
+				v = m.module.get(mx, my)
+			}
+
+			dots[y][x] = v
+
+			// Diffuse the error to the following sub-pixels.
+			e := darkness[y][x]
+			if v {
+				e--
+			}
+
+			diffuse := func(dx, dy int, w float64) {
+				if x+dx >= 0 && x+dx < subSize && y+dy < subSize {
+					darkness[y+dy][x+dx] += e * w
+				}
+			}
+
+			diffuse(1, 0, 7.0/16)
+			diffuse(-1, 1, 3.0/16)
+			diffuse(0, 1, 5.0/16)
+			diffuse(1, 1, 1.0/16)
+		}
+	}
+
+	return dots
+}
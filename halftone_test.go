@@ -0,0 +1,102 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// gradientPicture returns a picture shading from black on the left to white
+// on the right.
+func gradientPicture() image.Image {
+	picture := image.NewGray(image.Rect(0, 0, 100, 100))
+
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			picture.SetGray(x, y, color.Gray{uint8(x * 0xff / 99)})
+		}
+	}
+
+	return picture
+}
+
+func TestHalftone(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// 33 modules, 99 sub-pixels at 2px each.
+	img := q.Halftone(gradientPicture(), -2)
+
+	if img.Bounds().Dx() != 198 || img.Bounds().Dy() != 198 {
+		t.Fatalf("got size %v, expected 198x198", img.Bounds().Size())
+	}
+
+	bitmap := q.Bitmap()
+	functionPatterns := q.symbol.functionPatternBitmap()
+
+	sub := func(x, y int) bool {
+		return isDark(img.At(x*2, y*2))
+	}
+
+	// The centre of every module, and all of each solid module, is the
+	// module's value.
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			solid := functionPatterns[y][x] || !q.symbol.inSymbol(x, y)
+
+			for j := 0; j < 3; j++ {
+				for i := 0; i < 3; i++ {
+					if (solid || i == 1 && j == 1) && sub(x*3+i, y*3+j) != bitmap[y][x] {
+						t.Fatalf("module (%d,%d) sub-pixel (%d,%d) got %t, expected %t",
+							x, y, i, j, !bitmap[y][x], bitmap[y][x])
+					}
+				}
+			}
+		}
+	}
+
+	// The picture shows through: The surrounding sub-pixels of data modules
+	// are mostly dark on the left, and mostly light on the right.
+	var leftDark, leftTotal, rightDark, rightTotal int
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			if functionPatterns[y][x] || !q.symbol.inSymbol(x, y) {
+				continue
+			}
+
+			for j := 0; j < 3; j++ {
+				for i := 0; i < 3; i++ {
+					if i == 1 && j == 1 {
+						continue
+					}
+
+					dark := 0
+					if sub(x*3+i, y*3+j) {
+						dark = 1
+					}
+
+					switch {
+					case x < 10:
+						leftDark += dark
+						leftTotal++
+					case x >= 23:
+						rightDark += dark
+						rightTotal++
+					}
+				}
+			}
+		}
+	}
+
+	if leftDark*10 < leftTotal*7 {
+		t.Errorf("left side got %d/%d dark sub-pixels, expected mostly dark", leftDark, leftTotal)
+	}
+	if rightDark*10 > rightTotal*3 {
+		t.Errorf("right side got %d/%d dark sub-pixels, expected mostly light", rightDark, rightTotal)
+	}
+}
@@ -0,0 +1,113 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+)
+
+// lazyImage is an image.PalettedImage of a QR Code. The colour of each pixel
+// is computed from the symbol's modules when requested.
+type lazyImage struct {
+	palette color.Palette
+	rect    image.Rectangle
+
+	// Palette indexes of the data and finder pattern modules.
+	pixelIndex uint8
+	boxIndex   uint8
+
+	layout *pixelLayout
+	bitmap [][]bool
+	boxes  [][]bool
+}
+
+// LazyImage returns the QR Code as an image.PalettedImage, identical to the
+// image returned by Image, which computes each pixel on demand.
+//
+// No image buffer is allocated, so very large images can be passed to
+// image/draw, png.Encode or other streaming encoders cheaply. ScaleSmooth
+// scaling is not supported, and is drawn as ScaleInteger.
+//
+// See Image for the meaning of size.
+func (q *QRCode) LazyImage(size int) image.PalettedImage {
+	// Build QR code.
+	q.encode()
+
+	// Minimum pixels (both width and height) required.
+	realSize := q.symbol.size
+
+	// Variable size support.
+	if size < 0 {
+		size = size * -1 * realSize
+	}
+
+	// Actual pixels available to draw the symbol. Automatically increase the
+	// image size if it's not large enough.
+	if size < realSize {
+		size = realSize
+	}
+
+	// Same palette order as Image.
+	p := color.Palette([]color.Color{q.BackgroundColor, q.BoxColor, q.PixelColor})
+
+	return &lazyImage{
+		palette: p,
+		rect:    image.Rect(0, 0, size, size),
+
+		pixelIndex: uint8(p.Index(q.PixelColor)),
+		boxIndex:   uint8(p.Index(q.BoxColor)),
+
+		layout: newPixelLayout(size, realSize, q.Scaling),
+		bitmap: q.symbol.bitmap(),
+		boxes:  q.symbol.finderPatternBitmap(),
+	}
+}
+
+// ColorModel returns the image's palette.
+func (l *lazyImage) ColorModel() color.Model {
+	return l.palette
+}
+
+// Bounds returns the image's bounds.
+func (l *lazyImage) Bounds() image.Rectangle {
+	return l.rect
+}
+
+// At returns the colour of the pixel at (x, y).
+func (l *lazyImage) At(x, y int) color.Color {
+	return l.palette[l.ColorIndexAt(x, y)]
+}
+
+// Opaque returns true if all of the image's colours are opaque.
+func (l *lazyImage) Opaque() bool {
+	for _, c := range l.palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ColorIndexAt returns the palette index of the pixel at (x, y).
+func (l *lazyImage) ColorIndexAt(x, y int) uint8 {
+	if !(image.Point{x, y}.In(l.rect)) {
+		return 0
+	}
+
+	x2, y2 := l.layout.module[x], l.layout.module[y]
+	if x2 < 0 || y2 < 0 {
+		return 0
+	}
+
+	switch {
+	case l.boxes[y2][x2]:
+		return l.boxIndex
+	case l.bitmap[y2][x2]:
+		return l.pixelIndex
+	}
+
+	return 0
+}
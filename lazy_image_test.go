@@ -0,0 +1,89 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func TestLazyImageMatchesImage(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.BoxColor = color.RGBA{0xff, 0, 0, 0xff}
+
+	for _, scaling := range []ScaleMode{ScaleNearest, ScaleInteger} {
+		for _, size := range []int{-3, 33, 100, 257} {
+			q.Scaling = scaling
+
+			expected := q.Image(size).(*image.Paletted)
+			lazy := q.LazyImage(size)
+
+			if lazy.Bounds() != expected.Bounds() {
+				t.Fatalf("scaling=%d size=%d: got bounds %v, expected %v", scaling,
+					size, lazy.Bounds(), expected.Bounds())
+			}
+
+			b := expected.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					if lazy.ColorIndexAt(x, y) != expected.ColorIndexAt(x, y) {
+						t.Fatalf("scaling=%d size=%d: pixel (%d,%d) got index %d, expected %d",
+							scaling, size, x, y, lazy.ColorIndexAt(x, y), expected.ColorIndexAt(x, y))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLazyImageEncode(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, q.LazyImage(200)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Errorf("got %T, expected a paletted PNG", img)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	draw.Draw(dst, dst.Bounds(), q.LazyImage(200), image.Point{}, draw.Src)
+
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			if isDark(dst.At(x, y)) != isDark(img.At(x, y)) {
+				t.Fatalf("pixel (%d,%d) differs", x, y)
+			}
+		}
+	}
+}
+
+func BenchmarkLazyImagePNG(b *testing.B) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+
+	for n := 0; n < b.N; n++ {
+		var out bytes.Buffer
+		png.Encode(&out, q.LazyImage(2048))
+	}
+}
@@ -0,0 +1,278 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// LogoShape is the shape of the area cleared under a logo.
+type LogoShape int
+
+const (
+	// LogoBox clears a square of modules.
+	LogoBox LogoShape = iota
+
+	// LogoCircle clears every module touched by a circle.
+	LogoCircle
+)
+
+// LogoPlacement selects where a logo is placed.
+type LogoPlacement int
+
+const (
+	// LogoCentre places the logo at the centre of the symbol.
+	LogoCentre LogoPlacement = iota
+
+	// LogoAt places the logo at a given module position.
+	LogoAt
+)
+
+// LogoStyle sets how BeautifyImage clears the modules under the CenterLogo,
+// and where the logo is placed.
+//
+// The logo is snapped to the module grid. Every module touched by the cleared
+// area (the logo plus its padding) is hidden, so no modules are left half
+// covered. The cleared area is filled with the BackgroundColor, and the logo
+// is fitted within it.
+//
+// Use CheckLogo to check the logo is in a safe position, and the QR Code still
+// decodes with the modules hidden.
+type LogoStyle struct {
+	// Shape of the cleared area.
+	Shape LogoShape
+
+	// Width of the logo in modules. Zero sizes the logo as usual (from the
+	// image, up to 35% of the image width), rounded up to whole modules. A
+	// centred logo has an odd width, so is rounded up if necessary.
+	Size int
+
+	// Number of modules cleared around the logo.
+	Padding int
+
+	// Placement of the logo.
+	Placement LogoPlacement
+
+	// Position of the logo's top left module with LogoAt, counted from the
+	// top left module of the symbol (excluding the quiet zone). A position
+	// which would clear finder or timing patterns or format or version
+	// information, or extend outside the symbol, is unsafe: The logo is
+	// centred instead, and CheckLogo returns an error.
+	Position image.Point
+}
+
+// logoModules returns the modules (in the same layout as bitmap()) occupied
+// by the logo, and by the logo and its padding, in a size*size
+// BeautifyImage. An unsafe LogoAt position is replaced by the centre. The
+// symbol must already be encoded.
+func (q *QRCode) logoModules(size int) (logo, area image.Rectangle) {
+	if q.LogoStyle.Placement == LogoAt {
+		logo, area = q.placeLogo(size, LogoAt)
+		if q.checkLogoArea(area) == nil {
+			return logo, area
+		}
+	}
+
+	return q.placeLogo(size, LogoCentre)
+}
+
+// placeLogo returns the modules occupied by the logo, and by the logo and its
+// padding, with placement.
+func (q *QRCode) placeLogo(size int, placement LogoPlacement) (logo, area image.Rectangle) {
+	s := q.LogoStyle
+	m := q.symbol
+
+	n := s.Size
+	if n <= 0 {
+		n = int(math.Ceil(float64(q.centerLogoSize(size)*m.size) / float64(size)))
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	var min image.Point
+	switch placement {
+	case LogoAt:
+		min = s.Position.Add(image.Point{m.quietZoneSize, m.quietZoneSize})
+	default:
+		if n%2 != m.symbolSize%2 {
+			n++
+		}
+
+		c := m.quietZoneSize + (m.symbolSize-n)/2
+		min = image.Point{c, c}
+	}
+
+	logo = image.Rectangle{min, min.Add(image.Point{n, n})}
+	area = logo.Inset(-s.Padding)
+
+	return logo, area
+}
+
+// logoExcavation returns the modules (in the same layout as bitmap()) cleared
+// by the LogoStyle in a size*size BeautifyImage.
+func (q *QRCode) logoExcavation(size int) [][]bool {
+	_, area := q.logoModules(size)
+
+	return q.excavation(area)
+}
+
+// excavation returns the modules cleared by the LogoStyle's shape, for the
+// logo and padding occupying area.
+func (q *QRCode) excavation(area image.Rectangle) [][]bool {
+	realSize := q.symbol.size
+
+	cleared := make([][]bool, realSize)
+	for i := range cleared {
+		cleared[i] = make([]bool, realSize)
+	}
+
+	bounds := area.Intersect(image.Rect(0, 0, realSize, realSize))
+
+	// Centre and radius of the circle, in modules.
+	cx := float64(area.Min.X+area.Max.X) / 2
+	cy := float64(area.Min.Y+area.Max.Y) / 2
+	r := float64(area.Dx()) / 2
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if q.LogoStyle.Shape == LogoCircle {
+				// Distance from the centre to the nearest point of the module.
+				dx := math.Max(0, math.Max(float64(x)-cx, cx-float64(x+1)))
+				dy := math.Max(0, math.Max(float64(y)-cy, cy-float64(y+1)))
+
+				if dx*dx+dy*dy >= r*r {
+					continue
+				}
+			}
+
+			cleared[y][x] = true
+		}
+	}
+
+	return cleared
+}
+
+// drawStyledLogo clears the LogoStyle area of the size*size image img, and
+// draws the CenterLogo over it.
+func (q *QRCode) drawStyledLogo(img draw.Image, size int) {
+	modulesPerPixel := float64(q.symbol.size) / float64(size)
+	pixels := func(r image.Rectangle) image.Rectangle {
+		px := func(v int) int {
+			return int(math.Round(float64(v) / modulesPerPixel))
+		}
+
+		return image.Rect(px(r.Min.X), px(r.Min.Y), px(r.Max.X), px(r.Max.Y))
+	}
+
+	logoModules, _ := q.logoModules(size)
+	logoRect := pixels(logoModules)
+
+	// Whole modules are cleared, so no module is left partly covered. Each
+	// module's pixels run up to the next module's, covering the module drawn.
+	background := image.NewUniform(q.BackgroundColor)
+	cleared := q.logoExcavation(size)
+	for y := range cleared {
+		for x := range cleared[y] {
+			if cleared[y][x] {
+				fillRect(img, pixels(image.Rect(x, y, x+1, y+1)), background)
+			}
+		}
+	}
+
+	if q.styledLogoCache == nil {
+		q.styledLogoCache = make(map[image.Point]image.Image)
+	}
+
+	key := logoRect.Size()
+	logoFit, found := q.styledLogoCache[key]
+	if !found {
+		logoFit = imaging.Fit(*q.CenterLogo, key.X, key.Y, imaging.Lanczos)
+		q.styledLogoCache[key] = logoFit
+	}
+
+	// Centre the fitted logo within its modules.
+	offset := logoRect.Size().Sub(logoFit.Bounds().Size()).Div(2)
+	draw.Draw(img, logoRect.Add(offset), logoFit, image.Point{}, draw.Over)
+}
+
+// CheckLogo checks the CenterLogo of a size*size BeautifyImage is placed
+// safely, and the QR Code still decodes with the modules it hides.
+//
+// An error is returned if the logo extends outside the symbol, hides any
+// finder or timing patterns or format or version information, or hides more
+// codewords than error correction can recover. See AnalyzeLogo.
+func (q *QRCode) CheckLogo(size int) error {
+	if q.CenterLogo == nil {
+		return nil
+	}
+
+	q.encode()
+
+	if size < 0 {
+		size = size * -1 * q.symbol.size
+	}
+
+	if size < q.symbol.size {
+		size = q.symbol.size
+	}
+
+	if q.LogoStyle != nil {
+		// The position as given, rather than the centre it's replaced by.
+		_, area := q.placeLogo(size, q.LogoStyle.Placement)
+		if err := q.checkLogoArea(area); err != nil {
+			return err
+		}
+	}
+
+	covered := q.logoCoverage(size)
+	if err := q.checkLogoCoverage(covered); err != nil {
+		return err
+	}
+
+	if r := q.AnalyzeCoverage(covered); !r.OK {
+		return fmt.Errorf("logo hides too many codewords to be corrected (margin %d)", r.Margin)
+	}
+
+	return nil
+}
+
+// checkLogoArea returns an error if the LogoStyle area (the logo and its
+// padding) extends outside the symbol, or clears any function patterns other
+// than alignment patterns.
+func (q *QRCode) checkLogoArea(area image.Rectangle) error {
+	m := q.symbol
+	symbolArea := image.Rect(m.quietZoneSize, m.quietZoneSize,
+		m.quietZoneSize+m.symbolSize, m.quietZoneSize+m.symbolSize)
+
+	if !area.In(symbolArea) {
+		return errors.New("logo extends outside the symbol")
+	}
+
+	return q.checkLogoCoverage(q.excavation(area))
+}
+
+// checkLogoCoverage returns an error if covered includes any function
+// pattern modules other than alignment patterns.
+func (q *QRCode) checkLogoCoverage(covered [][]bool) error {
+	m := q.symbol
+	alignment := m.alignmentPatternBitmap()
+
+	for y := range covered {
+		for x := range covered[y] {
+			if covered[y][x] && m.functionModule.get(x, y) && !alignment[y][x] {
+				return fmt.Errorf("logo covers a function pattern at module (%d,%d)",
+					x-m.quietZoneSize, y-m.quietZoneSize)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// numCleared returns the number of cleared modules, and their bounds.
+func numCleared(cleared [][]bool) (int, image.Rectangle) {
+	var n int
+	var bounds image.Rectangle
+
+	for y := range cleared {
+		for x := range cleared[y] {
+			if cleared[y][x] {
+				n++
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return n, bounds
+}
+
+func TestLogoExcavation(t *testing.T) {
+	var logo image.Image = rectangleImage(100, 100, color.Black)
+
+	// Version 2: 25 modules, with a 4 module quiet zone.
+	tests := []struct {
+		style    LogoStyle
+		expected int
+		bounds   image.Rectangle
+	}{
+		{LogoStyle{Size: 3, Padding: 1}, 25, image.Rect(14, 14, 19, 19)},
+		{LogoStyle{Size: 4}, 25, image.Rect(14, 14, 19, 19)},
+		{LogoStyle{Shape: LogoCircle, Size: 5}, 25, image.Rect(14, 14, 19, 19)},
+		{LogoStyle{Shape: LogoCircle, Size: 5, Padding: 1}, 45, image.Rect(13, 13, 20, 20)},
+		{LogoStyle{Size: 2, Placement: LogoAt, Position: image.Point{10, 12}}, 4, image.Rect(14, 16, 16, 18)},
+
+		// Unsafe positions are replaced by the centre.
+		{LogoStyle{Size: 3, Placement: LogoAt, Position: image.Point{0, 0}}, 9, image.Rect(15, 15, 18, 18)},
+		{LogoStyle{Size: 3, Placement: LogoAt, Position: image.Point{23, 10}}, 9, image.Rect(15, 15, 18, 18)},
+	}
+
+	for i, test := range tests {
+		q, err := NewWithMinimumVersion("example", 2, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		style := test.style
+		q.CenterLogo = &logo
+		q.LogoStyle = &style
+		q.encode()
+
+		n, bounds := numCleared(q.logoCoverage(330))
+		if n != test.expected || bounds != test.bounds {
+			t.Errorf("test %d got %d modules cleared in %v, expected %d in %v",
+				i, n, bounds, test.expected, test.bounds)
+		}
+	}
+}
+
+func TestLogoStyleImage(t *testing.T) {
+	q, err := NewWithMinimumVersion("example", 2, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	var logo image.Image = rectangleImage(100, 100, red)
+	q.CenterLogo = &logo
+	q.LogoStyle = &LogoStyle{Size: 3, Padding: 1}
+
+	// 33 modules at 10px. The logo is modules 15-17, and its padding modules
+	// 14-18.
+	img := q.BeautifyImage(-10)
+
+	for y := 140; y < 190; y++ {
+		for x := 140; x < 190; x++ {
+			expected := white
+			if x >= 150 && x < 180 && y >= 150 && y < 180 {
+				expected = red
+			}
+
+			if c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); c != expected {
+				t.Fatalf("pixel (%d,%d) got %v, expected %v", x, y, c, expected)
+			}
+		}
+	}
+
+	// Just outside the padding, the modules are drawn as usual.
+	bitmap := q.Bitmap()
+	for i := 13; i < 20; i++ {
+		for _, p := range []image.Point{{i, 13}, {i, 19}, {13, i}, {19, i}} {
+			expected := white
+			if bitmap[p.Y][p.X] {
+				expected = color.RGBA{0, 0, 0, 0xff}
+			}
+
+			if c := color.RGBAModel.Convert(img.At(p.X*10+5, p.Y*10+5)).(color.RGBA); c != expected {
+				t.Errorf("module %v got %v, expected %v", p, c, expected)
+			}
+		}
+	}
+}
+
+func TestLogoCircleWholeModules(t *testing.T) {
+	// A transparent logo, so only the cleared area is drawn.
+	var logo image.Image = rectangleImage(100, 100, color.Transparent)
+
+	// Version 2: 33 modules at about 9.1px. The circle's edge crosses the
+	// alignment pattern at modules 20-24.
+	const size = 301
+	style := &LogoStyle{Shape: LogoCircle, Size: 5, Padding: 1, Placement: LogoAt, Position: image.Point{11, 11}}
+
+	plain, err := NewWithMinimumVersion("example", 2, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := plain.BeautifyImage(size).(*image.RGBA)
+
+	q, err := NewWithMinimumVersion("example", 2, Low)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.CenterLogo = &logo
+	q.LogoStyle = style
+	img := q.BeautifyImage(size).(*image.RGBA)
+
+	// Every module not cleared is drawn exactly as without the logo.
+	cleared := q.logoExcavation(size)
+	px := func(v int) int { return (v*size*10/33 + 5) / 10 }
+
+	for y := range cleared {
+		for x := range cleared[y] {
+			if cleared[y][x] {
+				continue
+			}
+
+			for py := px(y); py < px(y+1); py++ {
+				for pxl := px(x); pxl < px(x+1); pxl++ {
+					if img.RGBAAt(pxl, py) != expected.RGBAAt(pxl, py) {
+						t.Fatalf("module (%d,%d) pixel (%d,%d) got %v, expected %v",
+							x, y, pxl, py, img.RGBAAt(pxl, py), expected.RGBAAt(pxl, py))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLogoUnsafePosition(t *testing.T) {
+	var logo image.Image = rectangleImage(100, 100, color.Black)
+
+	draw := func(style *LogoStyle) *image.RGBA {
+		q, err := NewWithMinimumVersion("example", 2, Highest)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		q.CenterLogo = &logo
+		q.LogoStyle = style
+
+		return q.BeautifyImage(330).(*image.RGBA)
+	}
+
+	// A logo over the top left finder pattern is drawn centred instead.
+	unsafe := draw(&LogoStyle{Size: 3, Placement: LogoAt, Position: image.Point{1, 1}})
+	centred := draw(&LogoStyle{Size: 3})
+
+	if !bytes.Equal(unsafe.Pix, centred.Pix) {
+		t.Error("unsafe logo position not drawn centred")
+	}
+}
+
+func TestCheckLogo(t *testing.T) {
+	var logo image.Image = rectangleImage(100, 100, color.Black)
+
+	tests := []struct {
+		level    RecoveryLevel
+		style    *LogoStyle
+		expected string
+	}{
+		{Highest, &LogoStyle{Size: 3}, ""},
+		{Highest, &LogoStyle{Size: 3, Placement: LogoAt, Position: image.Point{9, 9}}, ""},
+		{Highest, &LogoStyle{Size: 3, Placement: LogoAt, Position: image.Point{0, 0}}, "function pattern"},
+		{Highest, &LogoStyle{Size: 3, Placement: LogoAt, Position: image.Point{23, 10}}, "outside the symbol"},
+		{Low, &LogoStyle{Size: 7}, "too many codewords"},
+	}
+
+	for i, test := range tests {
+		q, err := NewWithMinimumVersion("example", 2, test.level)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		q.CenterLogo = &logo
+		q.LogoStyle = test.style
+
+		err = q.CheckLogo(256)
+		switch {
+		case test.expected == "" && err != nil:
+			t.Errorf("test %d got error %q, expected none", i, err.Error())
+		case test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)):
+			t.Errorf("test %d got error %v, expected %q", i, err, test.expected)
+		}
+	}
+}
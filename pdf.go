@@ -0,0 +1,111 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// pointsPerInch is the number of PDF user space units (points) per inch.
+const pointsPerInch = 72
+
+// writePDF writes a single page PDF document to w. The page is width by height
+// points, and drawn by the content stream content.
+func writePDF(w io.Writer, width, height float64, content []byte) error {
+	var compressed bytes.Buffer
+
+	z := zlib.NewWriter(&compressed)
+	if _, err := z.Write(content); err != nil {
+		return err
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	var offsets []int
+
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\nendobj\n")
+	}
+
+	b.WriteString("%PDF-1.4\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << >> /Contents 4 0 R >>",
+		formatCoordinate(width), formatCoordinate(height))
+	object("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes())
+
+	// Cross-reference table, each entry exactly 20 bytes.
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writePDFPath writes content stream operators to fill p with c. Translucent
+// colours are composited over white.
+func writePDFPath(b *bytes.Buffer, p *Path, c color.Color) {
+	if p.Empty() {
+		return
+	}
+
+	r, g, bl, _ := flattenColor(c).RGBA()
+	fmt.Fprintf(b, "%s %s %s rg\n", formatCoordinate(float64(r)/0xffff),
+		formatCoordinate(float64(g)/0xffff), formatCoordinate(float64(bl)/0xffff))
+
+	point := func(pt [2]float64) {
+		b.WriteString(formatCoordinate(pt[0]))
+		b.WriteByte(' ')
+		b.WriteString(formatCoordinate(pt[1]))
+		b.WriteByte(' ')
+	}
+
+	// The current point, needed to convert quadratic curves to cubic.
+	var current [2]float64
+
+	for _, o := range p.ops {
+		switch o.op {
+		case pathMoveTo:
+			point(o.pts[0])
+			b.WriteString("m\n")
+		case pathLineTo:
+			point(o.pts[0])
+			b.WriteString("l\n")
+		case pathQuadTo:
+			ctrl, end := o.pts[0], o.pts[1]
+			point([2]float64{current[0] + 2.0/3*(ctrl[0]-current[0]), current[1] + 2.0/3*(ctrl[1]-current[1])})
+			point([2]float64{end[0] + 2.0/3*(ctrl[0]-end[0]), end[1] + 2.0/3*(ctrl[1]-end[1])})
+			point(end)
+			b.WriteString("c\n")
+		case pathCubeTo:
+			point(o.pts[0])
+			point(o.pts[1])
+			point(o.pts[2])
+			b.WriteString("c\n")
+		case pathClose:
+			b.WriteString("h\n")
+		}
+
+		if n := numPathOpPoints(o.op); n > 0 {
+			current = o.pts[n-1]
+		}
+	}
+
+	b.WriteString("f\n")
+}
@@ -0,0 +1,182 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import "math/bits"
+
+// Finder-like patterns scored by penalty3, as the 11 modules ending at a
+// module x, with bit j the module at x-j. The pattern is 1:1:3:1:1
+// (dark:light:dark:light:dark), with a light area 4 modules wide either
+// before or after it.
+const (
+	finderLikeLightBefore = 0x05d // 0000 1011101
+	finderLikeLightAfter  = 0x5d0 // 1011101 0000
+)
+
+// penalties returns the four penalty scores of the symbol, the same as
+// penalty1() to penalty4(), in a single pass over its rows and columns.
+//
+// The columns are read as the rows of the transposed symbol. Each row and
+// column is scored for penalty1 from the lengths of its runs of modules in the
+// same colour, and for penalty3 by matching the finder-like patterns a word at
+// a time.
+func (m *symbol) penalties() (p1, p2, p3, p4 int) {
+	start := m.quietZoneSize
+	end := start + m.symbolSize
+
+	columns := m.module.transpose()
+
+	// The modules which can end a run of the same colour, or a 2x2 block.
+	inside := m.symbolColumns(start + 1)
+
+	numDarkModules := 0
+
+	for y := start; y < end; y++ {
+		row := m.module.row(y)
+		column := columns.row(y)
+
+		p1 += runPenalty(row, inside, start, end) + runPenalty(column, inside, start, end)
+		p3 += finderLikePenalty(row, start, end) + finderLikePenalty(column, start, end)
+
+		if y > start {
+			p2 += sameColourBlocks(m.module.row(y-1), row, inside)
+		}
+
+		for _, w := range row {
+			numDarkModules += bits.OnesCount64(w)
+		}
+	}
+
+	return p1, p2 * penaltyWeight2, p3, m.darkModulePenalty(numDarkModules)
+}
+
+// runPenalty returns the penalty1 score of the modules from start to end of
+// row. inside has the modules set from start+1 to end.
+func runPenalty(row []uint64, inside []uint64, start int, end int) int {
+	penalty := 0
+
+	// Each module in a different colour to the one before it ends a run.
+	runStart := start
+	var carry uint64
+
+	for i, w := range row {
+		changes := (w ^ (w<<1 | carry)) & inside[i]
+		carry = w >> 63
+
+		for changes != 0 {
+			x := i*64 + bits.TrailingZeros64(changes)
+			changes &= changes - 1
+
+			if n := x - runStart; n >= 6 {
+				penalty += penaltyWeight1 + n - 5
+			}
+			runStart = x
+		}
+	}
+
+	if n := end - runStart; n >= 6 {
+		penalty += penaltyWeight1 + n - 5
+	}
+
+	return penalty
+}
+
+// finderLikePenalty returns the penalty3 score of the modules from start to
+// end of row. Modules outside of them are light.
+//
+// penalty3 reads the modules one at a time, and after each pattern found it
+// pretends the 8 modules ending there were dark, and the ones before light. So
+// a pattern can't be found again until 11 modules on, except for:
+//
+//   - a pattern with the light area after, 10 modules on: The first module of
+//     the pattern is then always pretended to be dark;
+//   - at the end of the row, where the light area is outside the symbol, a
+//     pattern 7 modules on (or 6, pretending its first module is dark).
+//
+// The patterns are matched against every module a word at a time, then read
+// in order to reproduce that.
+func finderLikePenalty(row []uint64, start int, end int) int {
+	penalty := 0
+
+	// Position of the last pattern found.
+	last := start - 11
+
+	for i, w := range row {
+		var previous uint64
+		if i > 0 {
+			previous = row[i-1]
+		}
+
+		// Modules matching the last j+1 modules of each pattern.
+		before, after := ^uint64(0), ^uint64(0)
+		var before6, before7, after10 uint64
+
+		for j := 0; j <= 10; j++ {
+			shifted := w << uint(j)
+			if j > 0 {
+				shifted |= previous >> uint(64-j)
+			}
+
+			// Modules differing from the pattern are cleared.
+			before &^= shifted ^ -(finderLikeLightBefore >> uint(j) & 1)
+			after &^= shifted ^ -(finderLikeLightAfter >> uint(j) & 1)
+
+			switch j {
+			case 5:
+				before6 = before
+			case 6:
+				before7 = before
+			case 9:
+				after10 = after
+			}
+		}
+
+		candidates := before | after | after10
+		if (end-1)/64 == i {
+			candidates |= before6 & (1 << uint((end-1)%64))
+		}
+
+		// Only the modules from start to end are read.
+		if i == start/64 {
+			candidates &^= 1<<uint(start%64) - 1
+		}
+		if i == (end-1)/64 {
+			candidates &= ^uint64(0) >> uint(63-(end-1)%64)
+		} else if i > (end-1)/64 {
+			candidates = 0
+		}
+
+		for candidates != 0 {
+			b := uint(bits.TrailingZeros64(candidates))
+			candidates &= candidates - 1
+
+			x := i*64 + int(b)
+			k := x - last
+
+			var found bool
+			switch {
+			case k >= 11:
+				found = (before|after)>>b&1 == 1
+			case k == 10:
+				found = after10>>b&1 == 1
+			}
+
+			if !found && x == end-1 {
+				switch {
+				case k >= 7:
+					found = before7>>b&1 == 1
+				case k == 6:
+					found = before6>>b&1 == 1
+				}
+			}
+
+			if found {
+				penalty += penaltyWeight3
+				last = x
+			}
+		}
+	}
+
+	return penalty
+}
@@ -0,0 +1,227 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// The penalty scores as calculated before they were optimised, a module at a
+// time, for reference.
+
+// referencePenalty1 returns the penalty score for "adjacent modules in
+// row/column with same colour".
+//
+// The numbers of adjacent matching modules and scores are:
+// 0-5: score = 0
+// 6+ : score = penaltyWeight1 + (numAdjacentModules - 5)
+func referencePenalty1(m *symbol) int {
+	penalty := 0
+
+	for x := 0; x < m.symbolSize; x++ {
+		lastValue := m.get(x, 0)
+		count := 1
+
+		for y := 1; y < m.symbolSize; y++ {
+			v := m.get(x, y)
+
+			if v != lastValue {
+				count = 1
+				lastValue = v
+			} else {
+				count++
+				if count == 6 {
+					penalty += penaltyWeight1 + 1
+				} else if count > 6 {
+					penalty++
+				}
+			}
+		}
+	}
+
+	for y := 0; y < m.symbolSize; y++ {
+		lastValue := m.get(0, y)
+		count := 1
+
+		for x := 1; x < m.symbolSize; x++ {
+			v := m.get(x, y)
+
+			if v != lastValue {
+				count = 1
+				lastValue = v
+			} else {
+				count++
+				if count == 6 {
+					penalty += penaltyWeight1 + 1
+				} else if count > 6 {
+					penalty++
+				}
+			}
+		}
+	}
+
+	return penalty
+}
+
+// referencePenalty2 returns the penalty score for "block of modules in the
+// same colour".
+//
+// m*n: score = penaltyWeight2 * (m-1) * (n-1).
+func referencePenalty2(m *symbol) int {
+	penalty := 0
+
+	for y := 1; y < m.symbolSize; y++ {
+		for x := 1; x < m.symbolSize; x++ {
+			topLeft := m.get(x-1, y-1)
+			above := m.get(x, y-1)
+			left := m.get(x-1, y)
+			current := m.get(x, y)
+
+			if current == left && current == above && current == topLeft {
+				penalty++
+			}
+		}
+	}
+
+	return penalty * penaltyWeight2
+}
+
+// referencePenalty3 returns the penalty score for "1:1:3:1:1 ratio
+// (dark:light:dark:light:dark) pattern in row/column, preceded or followed by
+// light area 4 modules wide".
+//
+// Existence of the pattern scores penaltyWeight3.
+func referencePenalty3(m *symbol) int {
+	penalty := 0
+
+	for y := 0; y < m.symbolSize; y++ {
+		var bitBuffer int16 = 0x00
+
+		for x := 0; x < m.symbolSize; x++ {
+			bitBuffer <<= 1
+			if v := m.get(x, y); v {
+				bitBuffer |= 1
+			}
+
+			switch bitBuffer & 0x7ff {
+			// 0b000 0101 1101 or 0b10111010000
+			// 0x05d           or 0x5d0
+			case 0x05d, 0x5d0:
+				penalty += penaltyWeight3
+				bitBuffer = 0xFF
+			default:
+				if x == m.symbolSize-1 && (bitBuffer&0x7f) == 0x5d {
+					penalty += penaltyWeight3
+					bitBuffer = 0xFF
+				}
+			}
+		}
+	}
+
+	for x := 0; x < m.symbolSize; x++ {
+		var bitBuffer int16 = 0x00
+
+		for y := 0; y < m.symbolSize; y++ {
+			bitBuffer <<= 1
+			if v := m.get(x, y); v {
+				bitBuffer |= 1
+			}
+
+			switch bitBuffer & 0x7ff {
+			// 0b000 0101 1101 or 0b10111010000
+			// 0x05d           or 0x5d0
+			case 0x05d, 0x5d0:
+				penalty += penaltyWeight3
+				bitBuffer = 0xFF
+			default:
+				if y == m.symbolSize-1 && (bitBuffer&0x7f) == 0x5d {
+					penalty += penaltyWeight3
+					bitBuffer = 0xFF
+				}
+			}
+		}
+	}
+
+	return penalty
+}
+
+// referencePenalty4 returns the penalty score for the proportion of dark
+// modules.
+func referencePenalty4(m *symbol) int {
+	numModules := m.symbolSize * m.symbolSize
+	numDarkModules := 0
+
+	for x := 0; x < m.symbolSize; x++ {
+		for y := 0; y < m.symbolSize; y++ {
+			if v := m.get(x, y); v {
+				numDarkModules++
+			}
+		}
+	}
+
+	numDarkModuleDeviation := numModules/2 - numDarkModules
+	if numDarkModuleDeviation < 0 {
+		numDarkModuleDeviation *= -1
+	}
+
+	return penaltyWeight4 * (numDarkModuleDeviation / (numModules / 20))
+}
+
+func TestPenalties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 5000; i++ {
+		size := 5 + r.Intn(100)
+		s := newSymbol(size, r.Intn(5))
+
+		// Mostly random modules, with some runs and finder-like patterns
+		// added, which random modules rarely have.
+		density := r.Float64()
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				s.set(x, y, r.Float64() < density)
+			}
+		}
+
+		patterns := [][]bool{
+			{b0, b0, b0, b0, b1, b0, b1, b1, b1, b0, b1},
+			{b1, b0, b1, b1, b1, b0, b1, b0, b0, b0, b0},
+			{b0, b1, b1, b1, b0, b1, b0, b0, b0, b0},
+			{b1, b1, b1, b1, b1, b1, b1},
+		}
+
+		for j := r.Intn(2 * size); j > 0; j-- {
+			pattern := patterns[r.Intn(len(patterns))]
+			if len(pattern) > size {
+				continue
+			}
+
+			x, y := r.Intn(size-len(pattern)+1), r.Intn(size)
+			vertical := r.Intn(2) == 0
+
+			for k, v := range pattern {
+				if vertical {
+					s.set(y, x+k, v)
+				} else {
+					s.set(x+k, y, v)
+				}
+			}
+		}
+
+		e1, e2, e3, e4 := referencePenalty1(s), referencePenalty2(s), referencePenalty3(s), referencePenalty4(s)
+
+		p1, p2, p3, p4 := s.penalties()
+		if p1 != e1 || p2 != e2 || p3 != e3 || p4 != e4 {
+			t.Fatalf("size %d got p1=%d, p2=%d, p3=%d, p4=%d (expected p1=%d, p2=%d, p3=%d, p4=%d)",
+				size, p1, p2, p3, p4, e1, e2, e3, e4)
+		}
+
+		p1, p2, p3, p4 = s.penalty1(), s.penalty2(), s.penalty3(), s.penalty4()
+		if p1 != e1 || p2 != e2 || p3 != e3 || p4 != e4 {
+			t.Fatalf("size %d penalty1-4 got p1=%d, p2=%d, p3=%d, p4=%d (expected p1=%d, p2=%d, p3=%d, p4=%d)",
+				size, p1, p2, p3, p4, e1, e2, e3, e4)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"math"
+)
+
+// Unit is a unit of physical length, used to size printed QR Codes.
+type Unit int
+
+const (
+	// Millimetre lengths.
+	Millimetre Unit = iota
+
+	// Inch lengths.
+	Inch
+)
+
+// millimetresPerInch converts between Millimetre and Inch lengths.
+const millimetresPerInch = 25.4
+
+// inches returns length (in unit) converted to inches.
+func (u Unit) inches(length float64) float64 {
+	if u == Millimetre {
+		return length / millimetresPerInch
+	}
+
+	return length
+}
+
+// PrintModuleSize returns the width/height in pixels of each module (QR Code
+// "pixel"), for an image printed length wide and high at dpi dots per inch.
+//
+// The length includes the quiet zone, unless DisableBorder is set. The module
+// size is always a whole number of pixels, rounded down so the printed code is
+// no larger than requested, with a minimum of 1px.
+func (q *QRCode) PrintModuleSize(length float64, unit Unit, dpi int) int {
+	// Build QR code.
+	q.encode()
+
+	pixels := unit.inches(length) * float64(dpi)
+	moduleSize := int(math.Floor(pixels / float64(q.symbol.size)))
+
+	if moduleSize < 1 {
+		moduleSize = 1
+	}
+
+	return moduleSize
+}
+
+// PrintImage returns the QR Code as an image.Image, sized to be printed length
+// wide and high at dpi dots per inch.
+//
+// Each module is a whole number of pixels (see PrintModuleSize), so the image
+// may be slightly smaller than requested.
+func (q *QRCode) PrintImage(length float64, unit Unit, dpi int) image.Image {
+	return q.Image(-q.PrintModuleSize(length, unit, dpi))
+}
+
+// EncodePrint writes the QR Code to w in the given image format, sized to be
+// printed length wide and high at dpi dots per inch.
+//
+// The resolution is recorded in the image metadata (a pHYs chunk for PNG, the
+// JFIF density for JPEG and the pixels per metre header fields for BMP), so
+// the code prints at the intended size. GIF and Netpbm images have no such
+// metadata. SVG images are given their physical size directly.
+func (q *QRCode) EncodePrint(w io.Writer, format Format, length float64,
+	unit Unit, dpi int) error {
+
+	if dpi <= 0 {
+		return errors.New("dpi must be positive")
+	}
+
+	if format == FormatSVG {
+		// Build QR code.
+		q.encode()
+
+		width := formatCoordinate(length) + "mm"
+		if unit == Inch {
+			width = formatCoordinate(length) + "in"
+		}
+
+		return q.writeSVG(w, width)
+	}
+
+	return q.encodeImage(w, q.PrintImage(length, unit, dpi), format, dpi)
+}
+
+// MinimumModuleSize returns the suggested minimum module size for a QR Code
+// scanned from distance away. The result is in the same unit as distance.
+//
+// The suggestion follows the common rule of thumb that a QR Code (excluding
+// its quiet zone) should be at least one tenth as wide as the scanning
+// distance. Larger codes, with more modules, therefore need larger physical
+// sizes.
+func (q *QRCode) MinimumModuleSize(distance float64) float64 {
+	const scanDistanceRatio = 10
+
+	return distance / scanDistanceRatio / float64(q.version.symbolSize())
+}
+
+// pixelsPerMetre returns dpi converted to pixels per metre.
+func pixelsPerMetre(dpi int) uint32 {
+	return uint32(math.Round(float64(dpi) * 1000 / millimetresPerInch))
+}
+
+// pngPHYs returns the data of a PNG pHYs chunk for dpi.
+func pngPHYs(dpi int) []byte {
+	ppm := pixelsPerMetre(dpi)
+
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint32(data[0:4], ppm)
+	binary.BigEndian.PutUint32(data[4:8], ppm)
+	data[8] = 1 // Unit is the metre.
+
+	return data
+}
+
+// writePNGWithDPI writes the PNG file b to w, with a pHYs chunk for dpi
+// inserted after the IHDR chunk.
+func writePNGWithDPI(w io.Writer, b []byte, dpi int) error {
+	// Signature, then the IHDR chunk: length, type, 13 bytes of data, CRC.
+	const ihdrEnd = len(pngSignature) + 4 + 4 + 13 + 4
+
+	if len(b) < ihdrEnd || string(b[len(pngSignature)+4:len(pngSignature)+8]) != "IHDR" {
+		return errors.New("bug: malformed PNG")
+	}
+
+	if _, err := w.Write(b[:ihdrEnd]); err != nil {
+		return err
+	}
+
+	if err := writePNGChunk(w, "pHYs", pngPHYs(dpi)); err != nil {
+		return err
+	}
+
+	_, err := w.Write(b[ihdrEnd:])
+	return err
+}
+
+// writeJPEGWithDPI writes the JPEG file b to w, with a JFIF APP0 segment
+// recording dpi inserted after the start of image marker.
+func writeJPEGWithDPI(w io.Writer, b []byte, dpi int) error {
+	if len(b) < 2 || b[0] != 0xff || b[1] != 0xd8 {
+		return errors.New("bug: malformed JPEG")
+	}
+
+	density := dpi
+	if density > math.MaxUint16 {
+		density = math.MaxUint16
+	}
+
+	app0 := []byte{
+		0xff, 0xe0, // APP0 marker.
+		0, 16, // Segment length.
+		'J', 'F', 'I', 'F', 0,
+		1, 1, // Version 1.01.
+		1,    // Density unit is dots per inch.
+		0, 0, // X density.
+		0, 0, // Y density.
+		0, 0, // No thumbnail.
+	}
+	binary.BigEndian.PutUint16(app0[12:14], uint16(density))
+	binary.BigEndian.PutUint16(app0[14:16], uint16(density))
+
+	for _, s := range [][]byte{b[:2], app0, b[2:]} {
+		if _, err := w.Write(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeBMPWithDPI writes the BMP file b to w, with the horizontal and vertical
+// resolution header fields set to dpi.
+func writeBMPWithDPI(w io.Writer, b []byte, dpi int) error {
+	// The 14 byte file header is followed by the info header, whose resolution
+	// fields start 24 bytes in.
+	const resolutionOffset = 14 + 24
+
+	if len(b) < resolutionOffset+8 || b[0] != 'B' || b[1] != 'M' {
+		return errors.New("bug: malformed BMP")
+	}
+
+	ppm := pixelsPerMetre(dpi)
+	binary.LittleEndian.PutUint32(b[resolutionOffset:], ppm)
+	binary.LittleEndian.PutUint32(b[resolutionOffset+4:], ppm)
+
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,139 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestPrintModuleSize(t *testing.T) {
+	// Version 2, 25 modules plus a 4 module quiet zone either side.
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		length   float64
+		unit     Unit
+		dpi      int
+		expected int
+	}{
+		// 30mm at 300dpi is 354.3px, 10.7px per module.
+		{30, Millimetre, 300, 10},
+		{1, Inch, 300, 9},
+		{1, Inch, 72, 2},
+		{1, Millimetre, 72, 1},
+	}
+
+	for _, test := range tests {
+		got := q.PrintModuleSize(test.length, test.unit, test.dpi)
+		if got != test.expected {
+			t.Errorf("%f (unit %d) at %ddpi: got %d, expected %d", test.length,
+				test.unit, test.dpi, got, test.expected)
+		}
+	}
+
+	img := q.PrintImage(30, Millimetre, 300)
+	if img.Bounds().Dx() != 330 {
+		t.Errorf("PrintImage got width %d, expected 330", img.Bounds().Dx())
+	}
+}
+
+// findPNGChunk returns the data of the first chunk of type typ.
+func findPNGChunk(b []byte, typ string) []byte {
+	b = b[len(pngSignature):]
+
+	for len(b) >= 12 {
+		length := int(binary.BigEndian.Uint32(b[0:4]))
+		if string(b[4:8]) == typ {
+			return b[8 : 8+length]
+		}
+
+		b = b[12+length:]
+	}
+
+	return nil
+}
+
+func TestEncodePrintDPI(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const dpi = 300
+	const ppm = 11811
+
+	for _, format := range []Format{FormatPNG, FormatPNG1Bit} {
+		var b bytes.Buffer
+		if err := q.EncodePrint(&b, format, 30, Millimetre, dpi); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		phys := findPNGChunk(b.Bytes(), "pHYs")
+		if len(phys) != 9 {
+			t.Fatalf("%s: pHYs chunk missing", format)
+		}
+
+		if x, y := binary.BigEndian.Uint32(phys[0:4]), binary.BigEndian.Uint32(phys[4:8]); x != ppm || y != ppm || phys[8] != 1 {
+			t.Errorf("%s: pHYs got %d x %d unit %d, expected %d x %d unit 1",
+				format, x, y, phys[8], ppm, ppm)
+		}
+
+		if _, err := png.Decode(&b); err != nil {
+			t.Errorf("%s: decode failed: %s", format, err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := q.EncodePrint(&b, FormatJPEG, 30, Millimetre, dpi); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	jfif := b.Bytes()[2:20]
+	if !bytes.Equal(jfif[:2], []byte{0xff, 0xe0}) || string(jfif[4:9]) != "JFIF\x00" ||
+		jfif[11] != 1 || binary.BigEndian.Uint16(jfif[12:14]) != dpi {
+		t.Errorf("JPEG got APP0 segment % x, expected JFIF at %ddpi", jfif, dpi)
+	}
+
+	if _, err := jpeg.Decode(&b); err != nil {
+		t.Errorf("JPEG decode failed: %s", err)
+	}
+
+	b.Reset()
+	if err := q.EncodePrint(&b, FormatBMP, 30, Millimetre, dpi); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if x := binary.LittleEndian.Uint32(b.Bytes()[38:42]); x != ppm {
+		t.Errorf("BMP got %d pixels per metre, expected %d", x, ppm)
+	}
+
+	if _, _, err := image.Decode(&b); err != nil {
+		t.Errorf("BMP decode failed: %s", err)
+	}
+
+	if err := q.EncodePrint(&b, FormatPNG, 30, Millimetre, 0); err == nil {
+		t.Error("EncodePrint with 0dpi succeeded, expected error")
+	}
+}
+
+func TestMinimumModuleSize(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// A 25 module code scanned from 500mm should be at least 50mm wide.
+	if got := q.MinimumModuleSize(500); math.Abs(got-2) > 1e-9 {
+		t.Errorf("MinimumModuleSize(500) got %f, expected 2", got)
+	}
+}
@@ -39,6 +39,11 @@ A size of -5 sets each module (QR Code "pixel") to be 5px wide/high.
 
 	err := qrcode.WriteFile("https://example.org", qrcode.Medium, -5, "qr.png")
 
+Other image formats (GIF, JPEG, BMP, PBM, PGM and 1-bit PNG) are written with
+QRCode.Encode:
+
+	err := q.Encode(w, qrcode.FormatGIF, 256)
+
 The maximum capacity of a QR Code varies according to the content encoded and
 the error recovery level. The maximum capacity is 2,953 bytes, 4,296
 alphanumeric characters, 7,089 numeric digits, or a combination of these.
@@ -57,10 +62,12 @@ import (
 	"image/draw"
 	"image/png"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"math"
 	"os"
+	"sync"
 
 	"github.com/disintegration/imaging"
 
@@ -146,9 +153,52 @@ type QRCode struct {
 	BoxColor                   color.Color
 	PixelColor                 color.Color
 
+	// How BeautifyImage clears the modules under the CenterLogo, and where
+	// the logo is placed. By default the logo is centred on a circular
+	// background, and only the modules under its opaque pixels are hidden.
+	LogoStyle *LogoStyle
+
+	// Shape of the data modules drawn by BeautifyImage and SVG. Defaults to
+	// squares.
+	ModuleShape ModuleShape
+
+	// Style of the finder patterns drawn by BeautifyImage and SVG. Defaults
+	// to plain squares. FinderPatternImage takes precedence in BeautifyImage.
+	FinderStyle *EyeStyle
+
+	// Style of the alignment patterns drawn by BeautifyImage and SVG, as a 5x5
+	// module ring and 1x1 module pupil. Colours default to PixelColor.
+	// AlignmentPatternImage takes precedence in BeautifyImage.
+	AlignmentStyle *EyeStyle
+
+	// Gradient fills drawn by BeautifyImage and SVG, in place of PixelColor
+	// (for all modules other than the finder patterns) and BoxColor (for the
+	// finder patterns). See CheckContrast.
+	DataGradient   *Gradient
+	FinderGradient *Gradient
+
+	// Photo drawn behind the QR Code by BeautifyImage, cropped to fill the
+	// image. The function patterns and quiet zone are drawn solid over it, and
+	// the data modules as set by BackgroundMode. BackgroundDotSize is the size
+	// of each BackgroundDots dot relative to the module size, defaulting to 0.5.
+	BackgroundImage   *image.Image
+	BackgroundMode    BackgroundMode
+	BackgroundDotSize float64
+
 	// Disable the QR Code border.
 	DisableBorder bool
 
+	// Evaluate the eight masks concurrently when the symbol is built, rather
+	// than one after another. The mask chosen is the same either way.
+	ParallelMasks bool
+
+	// Scaling of fixed size images. Defaults to ScaleNearest.
+	Scaling ScaleMode
+
+	// JPEG quality (1-100) used by Encode with FormatJPEG. Zero selects the
+	// default quality.
+	JPEGQuality int
+
 	encoder *dataEncoder
 	version qrCodeVersion
 
@@ -156,10 +206,16 @@ type QRCode struct {
 	symbol *symbol
 	mask   int
 
+	// If true, mask is used rather than choosing the mask with the lowest
+	// penalty score.
+	maskFixed bool
+
 	// cache for logo sizing
 	centerLogoCache            map[int]image.Image
+	styledLogoCache            map[image.Point]image.Image
 	finderPatternImageCache    map[int]image.Image
 	alignmentPatternImageCache map[int]image.Image
+	backgroundImageCache       map[int]image.Image
 }
 
 // New constructs a QRCode.
@@ -319,6 +375,14 @@ func (q *QRCode) Bitmap() [][]bool {
 // returned is the minimum size required for the QR Code. Choose a larger
 // negative number to increase the scale of the image. e.g. a size of -5 causes
 // each module (QR Code "pixel") to be 5px in size.
+//
+// How fixed size images are scaled is set by Scaling: See the documentation
+// for ScaleMode.
+//
+// The colours may be translucent, or fully transparent (e.g. a
+// color.Transparent background), for a QR Code to be placed over other
+// content. PNG images keep the alpha channel, GIF images keep fully
+// transparent pixels, and other formats are composited over white.
 func (q *QRCode) Image(size int) image.Image {
 	// Build QR code.
 	q.encode()
@@ -337,6 +401,10 @@ func (q *QRCode) Image(size int) image.Image {
 		size = realSize
 	}
 
+	if q.Scaling == ScaleSmooth {
+		return q.smoothImage(size)
+	}
+
 	// Output image.
 	rect := image.Rectangle{Min: image.Point{0, 0}, Max: image.Point{size, size}}
 
@@ -344,8 +412,8 @@ func (q *QRCode) Image(size int) image.Image {
 	p := color.Palette([]color.Color{q.BackgroundColor, q.BoxColor, q.PixelColor})
 	img := image.NewPaletted(rect, p)
 
-	// Map each image pixel to the nearest QR code module.
-	modulesPerPixel := float64(realSize) / float64(size)
+	// Map each image pixel to a QR code module.
+	layout := newPixelLayout(size, realSize, q.Scaling)
 
 	// QR code bitmap.
 	bitmap := q.symbol.bitmap()
@@ -353,9 +421,16 @@ func (q *QRCode) Image(size int) image.Image {
 	// color pixels
 	fgClr := uint8(img.Palette.Index(q.PixelColor))
 	for y := 0; y < size; y++ {
-		y2 := int(float64(y) * modulesPerPixel)
+		y2 := layout.module[y]
+		if y2 < 0 {
+			continue
+		}
+
 		for x := 0; x < size; x++ {
-			x2 := int(float64(x) * modulesPerPixel)
+			x2 := layout.module[x]
+			if x2 < 0 {
+				continue
+			}
 
 			v := bitmap[y2][x2]
 
@@ -372,9 +447,16 @@ func (q *QRCode) Image(size int) image.Image {
 	// color boxes
 	fgClr = uint8(img.Palette.Index(q.BoxColor))
 	for y := 0; y < size; y++ {
-		y2 := int(float64(y) * modulesPerPixel)
+		y2 := layout.module[y]
+		if y2 < 0 {
+			continue
+		}
+
 		for x := 0; x < size; x++ {
-			x2 := int(float64(x) * modulesPerPixel)
+			x2 := layout.module[x]
+			if x2 < 0 {
+				continue
+			}
 
 			v := boxes[y2][x2]
 
@@ -401,10 +483,18 @@ func (q *QRCode) Image(size int) image.Image {
 // returned is the minimum size required for the QR Code. Choose a larger
 // negative number to increase the scale of the image. e.g. a size of -5 causes
 // each module (QR Code "pixel") to be 5px in size.
+//
+// Colours may be translucent or fully transparent: Modules and the center logo
+// are alpha composited over the background.
 func (q *QRCode) BeautifyImage(size int) image.Image {
 	// Build QR code.
 	q.encode()
 
+	return q.beautifyImage(size)
+}
+
+// beautifyImage draws the encoded QR Code for BeautifyImage.
+func (q *QRCode) beautifyImage(size int) image.Image {
 	// Minimum pixels (both width and height) required.
 	realSize := q.symbol.size
 
@@ -426,38 +516,44 @@ func (q *QRCode) BeautifyImage(size int) image.Image {
 	img := image.NewRGBA(rect)
 
 	// set everything to background color
-	for x := 0; x < img.Bounds().Max.X; x++ {
-		for y := 0; y < img.Bounds().Max.Y; y++ {
-			img.Set(x, y, q.BackgroundColor)
-
-		}
-	}
+	draw.Draw(img, img.Bounds(), image.NewUniform(q.BackgroundColor), image.Point{}, draw.Src)
 
 	// Map each image pixel to the nearest QR code module.
 	modulesPerPixel := float64(realSize) / float64(size)
 	sizePerPoint := int(float64(size) / float64(realSize))
 
-	// undrawables
-	finderPatternMap := make(map[string]struct{})
-	alignmentPatternMap := make(map[string]struct{})
-	logoMap := make(map[string]struct{})
+	// Module fills.
+	pixelSrc := q.paint(q.PixelColor, q.DataGradient, size)
+	boxSrc := q.paint(q.BoxColor, q.FinderGradient, size)
 
-	// QR code finder pattern bitmap.
-	bitmap := q.symbol.finderPatternBitmap()
-	for y := 0; y < size; y++ {
-		y2 := int(float64(y) * modulesPerPixel)
-		for x := 0; x < size; x++ {
-			x2 := int(float64(x) * modulesPerPixel)
+	if q.BackgroundImage != nil {
+		q.drawBackgroundImage(img, size, newPixelLayout(size, realSize, ScaleNearest))
+	}
 
-			v := bitmap[y2][x2]
+	// Square modules are filled onto the background, unless there is a photo
+	// behind them, or (for data modules) a logo.
+	boxFill := q.overBackground(boxSrc, q.BackgroundImage == nil)
+	patternFill := q.overBackground(pixelSrc, q.BackgroundImage == nil)
+	pixelFill := q.overBackground(pixelSrc, q.BackgroundImage == nil && q.CenterLogo == nil)
 
-			if v {
-				pixel := fmt.Sprintf("%d,%d", y2, x2)
-				finderPatternMap[pixel] = struct{}{}
-			}
-		}
+	// The first pixel of each module column (or row).
+	positions := make([]int, realSize)
+	for i := range positions {
+		positions[i] = int(math.Round(float64(i) / modulesPerPixel))
+	}
+
+	// The pixels of module (x, y).
+	moduleRect := func(x, y int) image.Rectangle {
+		minX, minY := positions[x], positions[y]
+		return image.Rect(minX, minY, minX+sizePerPoint, minY+sizePerPoint)
 	}
 
+	// Modules drawn with the finder and alignment patterns, or hidden by the
+	// logo, which aren't drawn again with the data modules.
+	finderPatterns := q.symbol.finderPatternModule
+	alignmentPatterns := q.symbol.alignmentPatternModule
+	var logoCovered [][]bool
+
 	if q.FinderPatternImage != nil {
 		if q.finderPatternImageCache == nil {
 			q.finderPatternImageCache = make(map[int]image.Image)
@@ -491,58 +587,29 @@ func (q *QRCode) BeautifyImage(size int) image.Image {
 		TRMax := TRMin.Add(image.Point{boxSize, boxSize})
 		BLMax := BLMin.Add(image.Point{boxSize, boxSize})
 
-		for x := TLMin.X; x < TLMax.X; x++ {
-			for y := TLMin.Y; y < TLMax.Y; y++ {
-				img.Set(x, y, boxFit.At(x-TLMin.X, y-TLMin.Y))
-			}
-		}
-
-		for x := TRMin.X; x < TRMax.X; x++ {
-			for y := TRMin.Y; y < TRMax.Y; y++ {
-				img.Set(x, y, boxFit.At(x-TRMin.X, y-TRMin.Y))
-			}
-		}
-
-		for x := BLMin.X; x < BLMax.X; x++ {
-			for y := BLMin.Y; y < BLMax.Y; y++ {
-				img.Set(x, y, boxFit.At(x-BLMin.X, y-BLMin.Y))
-			}
-		}
-
+		draw.Draw(img, image.Rectangle{TLMin, TLMax}, boxFit, image.Point{}, draw.Src)
+		draw.Draw(img, image.Rectangle{TRMin, TRMax}, boxFit, image.Point{}, draw.Src)
+		draw.Draw(img, image.Rectangle{BLMin, BLMax}, boxFit, image.Point{}, draw.Src)
+	} else if q.FinderStyle != nil {
+		var outer, inner Path
+		q.FinderStyle.addFinderEyes(q.symbol, &outer, &inner, func(x, y int) (float64, float64) {
+			return math.Round(float64(x) / modulesPerPixel), math.Round(float64(y) / modulesPerPixel)
+		}, 1/modulesPerPixel)
+
+		outerSrc, innerSrc := q.FinderStyle.sources(boxSrc)
+		outer.draw(img, outerSrc)
+		inner.draw(img, innerSrc)
 	} else {
 
+		boxes := rectFiller{img: img, src: boxFill}
 		for x := 0; x < realSize; x++ {
 			for y := 0; y < realSize; y++ {
-				if bitmap[y][x] {
-
-					// find the box of pixels to light up
-					minX, minY := int(math.Round(float64(x)/modulesPerPixel)), int(math.Round(float64(y)/modulesPerPixel))
-					maxX, maxY := minX+sizePerPoint, minY+sizePerPoint
-
-					for xp := minX; xp < maxX; xp++ {
-						for yp := minY; yp < maxY; yp++ {
-							img.Set(xp, yp, q.BoxColor)
-						}
-					}
+				if finderPatterns.get(x, y) {
+					boxes.fill(moduleRect(x, y))
 				}
 			}
 		}
-	}
-
-	// QR code last alignment pattern bitmap.
-	bitmap = q.symbol.lastAlignmentPatternBitmap()
-	for y := 0; y < size; y++ {
-		y2 := int(float64(y) * modulesPerPixel)
-		for x := 0; x < size; x++ {
-			x2 := int(float64(x) * modulesPerPixel)
-
-			v := bitmap[y2][x2]
-
-			if v {
-				pixel := fmt.Sprintf("%d,%d", y2, x2)
-				alignmentPatternMap[pixel] = struct{}{}
-			}
-		}
+		boxes.flush()
 	}
 
 	if q.AlignmentPatternImage != nil {
@@ -563,163 +630,401 @@ func (q *QRCode) BeautifyImage(size int) image.Image {
 		}
 
 		borderSize := q.symbol.borderSize()
-		minPt := q.symbol.alignmentPatternPoint
+		for _, minPt := range q.symbol.alignmentPatternPoints {
+			minPt.X = int(float64(minPt.X+borderSize) / modulesPerPixel)
+			minPt.Y = int(float64(minPt.Y+borderSize) / modulesPerPixel)
 
-		minPt.X = int(float64(minPt.X+borderSize) / modulesPerPixel)
-		minPt.Y = int(float64(minPt.Y+borderSize) / modulesPerPixel)
+			maxPt := minPt.Add(image.Point{boxSize, boxSize})
 
-		maxPt := minPt.Add(image.Point{boxSize, boxSize})
-
-		for x := minPt.X; x < maxPt.X; x++ {
-			for y := minPt.Y; y < maxPt.Y; y++ {
-				img.Set(x, y, boxFit.At(x-minPt.X, y-minPt.Y))
-			}
+			draw.Draw(img, image.Rectangle{minPt, maxPt}, boxFit, image.Point{}, draw.Src)
 		}
-
+	} else if q.AlignmentStyle != nil {
+		var outer, inner Path
+		q.AlignmentStyle.addAlignmentEyes(q.symbol, &outer, &inner, func(x, y int) (float64, float64) {
+			return math.Round(float64(x) / modulesPerPixel), math.Round(float64(y) / modulesPerPixel)
+		}, 1/modulesPerPixel)
+
+		outerSrc, innerSrc := q.AlignmentStyle.sources(pixelSrc)
+		outer.draw(img, outerSrc)
+		inner.draw(img, innerSrc)
 	} else {
 
+		patterns := rectFiller{img: img, src: patternFill}
 		for x := 0; x < realSize; x++ {
 			for y := 0; y < realSize; y++ {
-				if bitmap[y][x] {
-
-					// find the box of pixels to light up
-					minX, minY := int(math.Round(float64(x)/modulesPerPixel)), int(math.Round(float64(y)/modulesPerPixel))
-					maxX, maxY := minX+sizePerPoint, minY+sizePerPoint
-
-					for xp := minX; xp < maxX; xp++ {
-						for yp := minY; yp < maxY; yp++ {
-							img.Set(xp, yp, q.PixelColor)
-						}
-					}
+				if alignmentPatterns.get(x, y) {
+					patterns.fill(moduleRect(x, y))
 				}
 			}
 		}
+		patterns.flush()
 	}
 
 	if q.CenterLogo != nil {
-		if q.centerLogoCache == nil {
-			q.centerLogoCache = make(map[int]image.Image)
-		}
+		if q.LogoStyle != nil {
+			q.drawStyledLogo(img, size)
+		} else {
+			logoFit, r := q.centerLogo(size)
 
-		logo := *q.CenterLogo
-		maxLogoSize := int(float64(size) * 0.35)
-		logoSize := logo.Bounds().Max.X
-		if logo.Bounds().Max.Y > logoSize {
-			logoSize = logo.Bounds().Max.Y
-		}
-		if logoSize > maxLogoSize {
-			logoSize = maxLogoSize
-		}
-		if logoSize%2 != 0 {
-			logoSize -= 1
+			// Composite the logo over the symbol drawn so far.
+			draw.Draw(img, r, logoFit, image.Point{}, draw.Over)
 		}
 
-		var logoFit image.Image
+		// Data modules hidden by the logo are not drawn.
+		logoCovered = q.logoCoverage(size)
+	}
 
-		if fittedLogo, found := q.centerLogoCache[logoSize]; found {
-			logoFit = fittedLogo
-		} else {
-			logoFitted := imaging.Fit(logo, logoSize, logoSize, imaging.Lanczos)
-			logoBackground := circleImage(logoSize/2+q.centerLogoBackgroundOffset, q.BackgroundColor)
-			logoFit = overlayImages(logoBackground, logoFitted, image.Point{-q.centerLogoBackgroundOffset, -q.centerLogoBackgroundOffset})
-			q.centerLogoCache[logoSize] = logoFit
-		}
+	// QR code bitmap.
+	bitmap := q.symbol.bitmap()
+	functionPatterns := q.symbol.functionModule
+
+	// Shaped data modules (and dots over a background photo), drawn together
+	// once all are added.
+	shaped := newMaskLayer(rect)
+	var photo *photoModules
+	if q.BackgroundImage != nil {
+		photo = q.newPhotoModules(shaped, pixelSrc)
+	}
+	modules := rectFiller{img: img, src: pixelFill}
 
-		minX := (size - logoFit.Bounds().Max.X) / 2
-		minY := (size - logoFit.Bounds().Max.Y) / 2
-		maxX := minX + logoFit.Bounds().Max.X
-		maxY := minY + logoFit.Bounds().Max.Y
-
-		for x := minX; x < maxX; x++ {
-			for y := minY; y < maxY; y++ {
-				r, g, b, a := logoFit.At(x-minX, y-minY).RGBA()
-				rI, gI, bI, aI := img.At(x, y).RGBA()
-				newCol := color.NRGBA{
-					R: uint8(r + rI),
-					G: uint8(g + gI),
-					B: uint8(b + bI),
-					A: uint8(a + aI),
-				}
+	for y := 0; y < realSize; y++ {
+		for x := 0; x < realSize; x++ {
+			// Light data modules are drawn too over a background photo.
+			photoModule := q.BackgroundImage != nil && !functionPatterns.get(x, y) && q.symbol.inSymbol(x, y)
 
-				if a == 65535 {
-					y2 := int(float64(y) * modulesPerPixel)
-					x2 := int(float64(x) * modulesPerPixel)
-					pixel := fmt.Sprintf("%d,%d", y2, x2)
-					logoMap[pixel] = struct{}{}
-					img.Set(x, y, newCol)
-				}
+			if !bitmap[y][x] && !photoModule ||
+				finderPatterns.get(x, y) || alignmentPatterns.get(x, y) ||
+				logoCovered != nil && logoCovered[y][x] {
+				continue
+			}
+
+			r := moduleRect(x, y)
+
+			switch {
+			case photoModule:
+				q.drawPhotoModule(img, photo, r, bitmap[y][x])
+			case q.ModuleShape != nil && !functionPatterns.get(x, y):
+				shaped.addModule(q.ModuleShape, float64(r.Min.X), float64(r.Min.Y),
+					float64(sizePerPoint), neighbors(bitmap, x, y))
+			default:
+				modules.fill(r)
 			}
 		}
+	}
+	modules.flush()
 
+	if photo != nil {
+		photo.light.draw(img, photo.lightSrc)
 	}
+	shaped.draw(img, pixelSrc)
 
-	// QR code bitmap.
-	bitmap = q.symbol.bitmap()
-	for x := 0; x < realSize; x++ {
-		for y := 0; y < realSize; y++ {
-			if bitmap[y][x] {
-				pixel := fmt.Sprintf("%d,%d", y, x)
-				if _, found := finderPatternMap[pixel]; !found {
-					if _, found = alignmentPatternMap[pixel]; !found {
-						if _, found = logoMap[pixel]; !found {
-
-							// find the box of pixels to light up
-							minX, minY := int(math.Round(float64(x)/modulesPerPixel)), int(math.Round(float64(y)/modulesPerPixel))
-							maxX, maxY := minX+sizePerPoint, minY+sizePerPoint
-
-							for xp := minX; xp < maxX; xp++ {
-								for yp := minY; yp < maxY; yp++ {
-									img.Set(xp, yp, q.PixelColor)
-								}
-							}
-
-						}
-					}
-				}
+	return img
+}
+
+// centerLogo returns the CenterLogo fitted to a size*size BeautifyImage, on
+// its circular background, and the rectangle it's drawn in.
+func (q *QRCode) centerLogo(size int) (image.Image, image.Rectangle) {
+	if q.centerLogoCache == nil {
+		q.centerLogoCache = make(map[int]image.Image)
+	}
+
+	logo := *q.CenterLogo
+	logoSize := q.centerLogoSize(size)
+
+	var logoFit image.Image
+
+	if fittedLogo, found := q.centerLogoCache[logoSize]; found {
+		logoFit = fittedLogo
+	} else {
+		logoFitted := imaging.Fit(logo, logoSize, logoSize, imaging.Lanczos)
+		logoBackground := circleImage(logoSize/2+q.centerLogoBackgroundOffset, q.BackgroundColor)
+		logoFit = overlayImages(logoBackground, logoFitted, image.Point{-q.centerLogoBackgroundOffset, -q.centerLogoBackgroundOffset})
+		q.centerLogoCache[logoSize] = logoFit
+	}
+
+	minX := (size - logoFit.Bounds().Max.X) / 2
+	minY := (size - logoFit.Bounds().Max.Y) / 2
+
+	return logoFit, image.Rect(minX, minY, minX+logoFit.Bounds().Max.X, minY+logoFit.Bounds().Max.Y)
+}
+
+// centerLogoSize returns the width in pixels of the CenterLogo (excluding its
+// background) in a size*size BeautifyImage. The logo is limited to 35% of the
+// image.
+func (q *QRCode) centerLogoSize(size int) int {
+	logo := *q.CenterLogo
+	maxLogoSize := int(float64(size) * 0.35)
+	logoSize := logo.Bounds().Max.X
+	if logo.Bounds().Max.Y > logoSize {
+		logoSize = logo.Bounds().Max.Y
+	}
+	if logoSize > maxLogoSize {
+		logoSize = maxLogoSize
+	}
+	if logoSize%2 != 0 {
+		logoSize -= 1
+	}
+
+	return logoSize
+}
+
+// logoCoverage returns the modules (in the same layout as bitmap()) hidden by
+// the CenterLogo of a size*size BeautifyImage: Those cleared by the LogoStyle,
+// or by default those under opaque pixels of the logo. The symbol must
+// already be encoded.
+func (q *QRCode) logoCoverage(size int) [][]bool {
+	if q.LogoStyle != nil {
+		return q.logoExcavation(size)
+	}
+
+	realSize := q.symbol.size
+	modulesPerPixel := float64(realSize) / float64(size)
+
+	covered := make([][]bool, realSize)
+	for i := range covered {
+		covered[i] = make([]bool, realSize)
+	}
+
+	logoFit, r := q.centerLogo(size)
+
+	// The module column of each pixel column.
+	columns := make([]int, r.Dx())
+	for x := range columns {
+		columns[x] = int(float64(x+r.Min.X) * modulesPerPixel)
+	}
+
+	// The fitted logo is read directly, when it's an *image.RGBA.
+	rgba, _ := logoFit.(*image.RGBA)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := covered[int(float64(y)*modulesPerPixel)]
+
+		for x, column := range columns {
+			var opaque bool
+			if rgba != nil {
+				opaque = rgba.Pix[rgba.PixOffset(x, y-r.Min.Y)+3] == 0xff
+			} else {
+				_, _, _, a := logoFit.At(x, y-r.Min.Y).RGBA()
+				opaque = a == 0xffff
+			}
+
+			if opaque {
+				row[column] = true
 			}
 		}
 	}
 
-	return img
+	return covered
+}
+
+// SetCenterLogo sets the CenterLogo, drawn on a circular background offset
+// pixels wider than the logo.
+func (q *QRCode) SetCenterLogo(img image.Image, offset int) {
+	q.CenterLogo = &img
+	q.centerLogoBackgroundOffset = offset
+	q.centerLogoCache = nil
+	q.styledLogoCache = nil
 }
 
+// LoadAndSetCenterLogo loads the image at path as the CenterLogo. See
+// SetCenterLogo.
 func (q *QRCode) LoadAndSetCenterLogo(path string, offset int) error {
 	img, err := loadImage(path)
 	if err == nil {
-		q.CenterLogo = img
-		q.centerLogoBackgroundOffset = offset
+		q.SetCenterLogo(img, offset)
+	}
+	return err
+}
+
+// ReadAndSetCenterLogo decodes the image read from r as the CenterLogo. See
+// SetCenterLogo.
+func (q *QRCode) ReadAndSetCenterLogo(r io.Reader, offset int) error {
+	img, err := readImage(r)
+	if err == nil {
+		q.SetCenterLogo(img, offset)
+	}
+	return err
+}
+
+// LoadAndSetCenterLogoFS loads the image at path in fsys (e.g. an embed.FS) as
+// the CenterLogo. See SetCenterLogo.
+func (q *QRCode) LoadAndSetCenterLogoFS(fsys fs.FS, path string, offset int) error {
+	img, err := loadImageFS(fsys, path)
+	if err == nil {
+		q.SetCenterLogo(img, offset)
 	}
 	return err
 }
 
+// SetFinderPatternImage sets the FinderPatternImage.
+func (q *QRCode) SetFinderPatternImage(img image.Image) {
+	q.FinderPatternImage = &img
+	q.finderPatternImageCache = nil
+}
+
+// LoadAndSetFinderPatternImage loads the image at path as the
+// FinderPatternImage.
 func (q *QRCode) LoadAndSetFinderPatternImage(path string) error {
 	img, err := loadImage(path)
 	if err == nil {
-		q.FinderPatternImage = img
+		q.SetFinderPatternImage(img)
+	}
+	return err
+}
+
+// ReadAndSetFinderPatternImage decodes the image read from r as the
+// FinderPatternImage.
+func (q *QRCode) ReadAndSetFinderPatternImage(r io.Reader) error {
+	img, err := readImage(r)
+	if err == nil {
+		q.SetFinderPatternImage(img)
+	}
+	return err
+}
+
+// LoadAndSetFinderPatternImageFS loads the image at path in fsys as the
+// FinderPatternImage.
+func (q *QRCode) LoadAndSetFinderPatternImageFS(fsys fs.FS, path string) error {
+	img, err := loadImageFS(fsys, path)
+	if err == nil {
+		q.SetFinderPatternImage(img)
 	}
 	return err
 }
 
+// SetAlignmentPatternImage sets the AlignmentPatternImage.
+func (q *QRCode) SetAlignmentPatternImage(img image.Image) {
+	q.AlignmentPatternImage = &img
+	q.alignmentPatternImageCache = nil
+}
+
+// LoadAndSetAlignmentPatternImage loads the image at path as the
+// AlignmentPatternImage.
 func (q *QRCode) LoadAndSetAlignmentPatternImage(path string) error {
 	img, err := loadImage(path)
 	if err == nil {
-		q.AlignmentPatternImage = img
+		q.SetAlignmentPatternImage(img)
+	}
+	return err
+}
+
+// ReadAndSetAlignmentPatternImage decodes the image read from r as the
+// AlignmentPatternImage.
+func (q *QRCode) ReadAndSetAlignmentPatternImage(r io.Reader) error {
+	img, err := readImage(r)
+	if err == nil {
+		q.SetAlignmentPatternImage(img)
 	}
 	return err
 }
 
-func loadImage(path string) (*image.Image, error) {
-	file, openErr := os.Open(path)
-	if openErr != nil {
-		return nil, openErr
+// LoadAndSetAlignmentPatternImageFS loads the image at path in fsys as the
+// AlignmentPatternImage.
+func (q *QRCode) LoadAndSetAlignmentPatternImageFS(fsys fs.FS, path string) error {
+	img, err := loadImageFS(fsys, path)
+	if err == nil {
+		q.SetAlignmentPatternImage(img)
+	}
+	return err
+}
+
+// loadImage decodes the image file at path.
+func loadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readImage(file)
+}
+
+// loadImageFS decodes the image file at path in fsys.
+func loadImageFS(fsys fs.FS, path string) (image.Image, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readImage(file)
+}
+
+// readImage decodes the image read from r. The image format must be
+// registered, as for image.Decode.
+func readImage(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// overBackground returns the source to fill modules with src, when onBackground
+// says they are filled onto the plain background colour. A translucent colour
+// always composites to the same colour over the background, so it is
+// composited once, and the modules filled with the result.
+func (q *QRCode) overBackground(src image.Image, onBackground bool) image.Image {
+	c, ok := src.(*image.Uniform)
+	if !ok || !onBackground {
+		return src
 	}
 
-	logo, _, decodeErr := image.Decode(file)
-	if decodeErr != nil {
-		return nil, decodeErr
+	// Over a translucent background the result would be translucent too, and
+	// not the same drawn over the background again.
+	_, _, _, a := c.RGBA()
+	_, _, _, backgroundA := q.BackgroundColor.RGBA()
+	if a == 0xffff || backgroundA != 0xffff {
+		return src
+	}
+
+	pixel := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	draw.Draw(pixel, pixel.Rect, image.NewUniform(q.BackgroundColor), image.Point{}, draw.Src)
+	draw.Draw(pixel, pixel.Rect, src, image.Point{}, draw.Over)
+
+	return image.NewUniform(pixel.RGBAAt(0, 0))
+}
+
+// fillRect composites src over the rectangle r of img. src is in the same
+// coordinate space as img. Translucent colours are blended with the pixels
+// already drawn.
+func fillRect(img draw.Image, r image.Rectangle, src image.Image) {
+	draw.Draw(img, r, src, r.Min, draw.Over)
+}
+
+// A rectFiller fills rectangles of img with src, as fillRect does. Each
+// rectangle directly beside or below the one before is merged into it, to fill
+// rows or columns of modules at once. The rectangles must not overlap.
+type rectFiller struct {
+	img draw.Image
+	src image.Image
+
+	// The rectangle not yet filled.
+	r image.Rectangle
+}
+
+// fill fills r, or merges it into the rectangle not yet filled.
+func (f *rectFiller) fill(r image.Rectangle) {
+	switch {
+	case r.Min.X == f.r.Max.X && r.Min.Y == f.r.Min.Y && r.Max.Y == f.r.Max.Y:
+		f.r.Max.X = r.Max.X
+		return
+	case r.Min.Y == f.r.Max.Y && r.Min.X == f.r.Min.X && r.Max.X == f.r.Max.X:
+		f.r.Max.Y = r.Max.Y
+		return
 	}
 
-	return &logo, nil
+	f.flush()
+	f.r = r
+}
+
+// flush fills the rectangle not yet filled.
+func (f *rectFiller) flush() {
+	if !f.r.Empty() {
+		fillRect(f.img, f.r, f.src)
+	}
+	f.r = image.Rectangle{}
 }
 
 func overlayImages(base, overlay image.Image, offset image.Point) image.Image {
@@ -843,35 +1148,64 @@ func (q *QRCode) encode() {
 
 	encoded := q.encodeBlocks()
 
+	// The function patterns and data are the same for every mask, so are
+	// added just once.
+	base := newRegularSymbol(q.version, 0, encoded, !q.DisableBorder)
+	base.addUnmaskedData()
+
+	numEmptyModules := base.symbol.numEmptyModules()
+	if numEmptyModules != 0 {
+		log.Panicf("bug: numEmptyModules is %d (expected 0) (version=%d)",
+			numEmptyModules, q.VersionNumber)
+	}
+
 	const numMasks int = 8
-	penalty := 0
+	var symbols [numMasks]*symbol
+	var penalties [numMasks]int
 
-	for mask := 0; mask < numMasks; mask++ {
-		var s *symbol
-		var err error
+	evaluate := func(mask int) {
+		s := base.withMask(mask, base.symbol.maskPattern(mask)).symbol
 
-		s, err = buildRegularSymbol(q.version, mask, encoded, !q.DisableBorder)
+		symbols[mask] = s
+		penalties[mask] = s.penaltyScore()
+	}
 
-		if err != nil {
-			log.Panic(err.Error())
+	var wg sync.WaitGroup
+	for mask := 0; mask < numMasks; mask++ {
+		if q.maskFixed && mask != q.mask {
+			continue
 		}
 
-		numEmptyModules := s.numEmptyModules()
-		if numEmptyModules != 0 {
-			log.Panicf("bug: numEmptyModules is %d (expected 0) (version=%d)",
-				numEmptyModules, q.VersionNumber)
+		if !q.ParallelMasks {
+			evaluate(mask)
+			continue
 		}
 
-		p := s.penaltyScore()
+		wg.Add(1)
+		go func(mask int) {
+			defer wg.Done()
+			evaluate(mask)
+		}(mask)
+	}
+	wg.Wait()
 
-		// log.Printf("mask=%d p=%3d p1=%3d p2=%3d p3=%3d p4=%d\n", mask, p, s.penalty1(), s.penalty2(), s.penalty3(), s.penalty4())
+	var best *symbol
+	penalty := 0
+	for mask, s := range symbols {
+		if s == nil {
+			continue
+		}
+
+		// log.Printf("mask=%d p=%3d p1=%3d p2=%3d p3=%3d p4=%d\n", mask, penalties[mask], s.penalty1(), s.penalty2(), s.penalty3(), s.penalty4())
 
-		if q.symbol == nil || p < penalty {
-			q.symbol = s
+		if best == nil || penalties[mask] < penalty {
+			best = s
 			q.mask = mask
-			penalty = p
+			penalty = penalties[mask]
 		}
 	}
+
+	q.symbol = best
 }
 
 // addTerminatorBits adds final terminator bits to the encoded data.
@@ -890,9 +1224,9 @@ func (q *QRCode) addTerminatorBits(numTerminatorBits int) {
 //
 // The QR Code's final data sequence is returned.
 func (q *QRCode) encodeBlocks() *bitset.Bitset {
-	// Split into blocks.
+	// Split into blocks of codewords.
 	type dataBlock struct {
-		data          *bitset.Bitset
+		data          []byte
 		ecStartOffset int
 	}
 
@@ -909,8 +1243,8 @@ func (q *QRCode) encodeBlocks() *bitset.Bitset {
 
 			// Apply error correction to each block.
 			numErrorCodewords := b.numCodewords - b.numDataCodewords
-			block[blockID].data = reedsolomon.Encode(q.data.Substr(start, end), numErrorCodewords)
-			block[blockID].ecStartOffset = end - start
+			block[blockID].data = reedsolomon.EncodeBytes(q.data.SubstrBytes(start, end), numErrorCodewords)
+			block[blockID].ecStartOffset = b.numDataCodewords
 
 			blockID++
 		}
@@ -918,11 +1252,11 @@ func (q *QRCode) encodeBlocks() *bitset.Bitset {
 
 	// Interleave the blocks.
 
-	result := bitset.New()
+	codewords := make([]byte, 0, q.version.numDataBits()/8+q.version.numECCodewords())
 
 	// Combine data blocks.
 	working := true
-	for i := 0; working; i += 8 {
+	for i := 0; working; i++ {
 		working = false
 
 		for j, b := range block {
@@ -930,7 +1264,7 @@ func (q *QRCode) encodeBlocks() *bitset.Bitset {
 				continue
 			}
 
-			result.Append(b.data.Substr(i, i+8))
+			codewords = append(codewords, b.data[i])
 
 			working = true
 		}
@@ -938,21 +1272,24 @@ func (q *QRCode) encodeBlocks() *bitset.Bitset {
 
 	// Combine error correction blocks.
 	working = true
-	for i := 0; working; i += 8 {
+	for i := 0; working; i++ {
 		working = false
 
 		for j, b := range block {
 			offset := i + block[j].ecStartOffset
-			if offset >= block[j].data.Len() {
+			if offset >= len(block[j].data) {
 				continue
 			}
 
-			result.Append(b.data.Substr(offset, offset+8))
+			codewords = append(codewords, b.data[offset])
 
 			working = true
 		}
 	}
 
+	result := bitset.New()
+	result.AppendBytes(codewords)
+
 	// Append remainder bits.
 	result.AppendNumBools(q.version.numRemainderBits, false)
 
@@ -6,6 +6,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -13,11 +14,13 @@ import (
 )
 
 func main() {
-	outFile := flag.String("o", "", "out PNG file prefix, empty for stdout")
+	outFile := flag.String("o", "", "out file name, empty for stdout. The image format is chosen\nfrom the extension (.png, .gif, .jpg, .bmp, .pbm, .pgm, .svg); PNG is\nwritten if there is none")
 	size := flag.Int("s", 256, "image size (pixel)")
 	textArt := flag.Bool("t", false, "print as text-art on stdout")
 	negative := flag.Bool("i", false, "invert black and white")
 	disableBorder := flag.Bool("d", false, "disable QR Code border")
+	oneBit := flag.Bool("1bit", false, "write PNG images in the 1-bit black and white format, about half\nthe size")
+	styleFile := flag.String("style", "", "style file (JSON) setting the colours, module shapes, logo, frame\netc. See the documentation for qrcode.Style")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `qrcode -- QR Code encoder in Go
 https://github.com/skip2/go-qrcode
@@ -37,6 +40,10 @@ Usage:
 
        qrcode "homepage: https://github.com/skip2/go-qrcode" > out.png
 
+  3. Draw with a style saved as JSON, e.g. {"module_shape": "circle"}:
+
+       qrcode --style style.json -o out.svg https://example.org
+
 `)
 	}
 	flag.Parse()
@@ -53,6 +60,15 @@ Usage:
 	q, err = qrcode.New(content, qrcode.Highest)
 	checkError(err)
 
+	var style *qrcode.Style
+	if *styleFile != "" {
+		style, err = qrcode.LoadStyle(*styleFile)
+		checkError(err)
+
+		err = q.ApplyStyle(style)
+		checkError(err)
+	}
+
 	if *disableBorder {
 		q.DisableBorder = true
 	}
@@ -67,19 +83,69 @@ Usage:
 		q.PixelColor, q.BackgroundColor = q.BackgroundColor, q.PixelColor
 	}
 
-	var png []byte
-	png, err = q.PNG(*size)
-	checkError(err)
+	filename, format := outputFormat(*outFile, *oneBit)
 
-	if *outFile == "" {
-		os.Stdout.Write(png)
-	} else {
-		var fh *os.File
-		fh, err = os.Create(*outFile + ".png")
+	if filename == "" {
+		err = encode(os.Stdout, q, style, format, *size)
 		checkError(err)
-		defer fh.Close()
-		fh.Write(png)
+		return
+	}
+
+	var fh *os.File
+	fh, err = os.Create(filename)
+	checkError(err)
+	defer fh.Close()
+
+	err = encode(fh, q, style, format, *size)
+	checkError(err)
+}
+
+// outputFormat returns the file name to write, and its format. The format is
+// chosen from the extension, and ".png" is added if there is none. PNG images
+// are written in the 1-bit format if oneBit is set. An empty filename is
+// standard output, which is written as PNG.
+func outputFormat(filename string, oneBit bool) (string, qrcode.Format) {
+	format := qrcode.FormatPNG
+
+	if filename != "" {
+		var err error
+		if format, err = qrcode.FormatFromFilename(filename); err != nil {
+			filename += ".png"
+			format = qrcode.FormatPNG
+		}
+	}
+
+	if oneBit && format == qrcode.FormatPNG {
+		format = qrcode.FormatPNG1Bit
+	}
+
+	return filename, format
+}
+
+// encode writes the QR Code to w. With a style, the QR Code is drawn by
+// BeautifyImage, with the style's frame if any.
+func encode(w io.Writer, q *qrcode.QRCode, style *qrcode.Style, format qrcode.Format, size int) error {
+	if style == nil {
+		return q.Encode(w, format, size)
 	}
+
+	frame, err := style.LoadFrame()
+	if err != nil {
+		return err
+	}
+
+	if format == qrcode.FormatSVG {
+		return q.WriteFramedSVG(w, size, frame)
+	}
+
+	img := q.BeautifyImage(size)
+	if frame != nil {
+		if img, err = q.FrameImage(img, frame); err != nil {
+			return err
+		}
+	}
+
+	return q.EncodeImage(w, img, format)
 }
 
 func checkError(err error) {
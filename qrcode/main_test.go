@@ -0,0 +1,36 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package main
+
+import (
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		oneBit   bool
+		expected string
+		format   qrcode.Format
+	}{
+		{"", false, "", qrcode.FormatPNG},
+		{"", true, "", qrcode.FormatPNG1Bit},
+		{"qr.png", false, "qr.png", qrcode.FormatPNG},
+		{"qr.png", true, "qr.png", qrcode.FormatPNG1Bit},
+		{"qr", true, "qr.png", qrcode.FormatPNG1Bit},
+		{"qr.gif", true, "qr.gif", qrcode.FormatGIF},
+		{"qr.svg", false, "qr.svg", qrcode.FormatSVG},
+	}
+
+	for _, test := range tests {
+		filename, format := outputFormat(test.filename, test.oneBit)
+
+		if filename != test.expected || format != test.format {
+			t.Errorf("%q (1-bit %t): got %q as %s, expected %q as %s",
+				test.filename, test.oneBit, filename, format, test.expected, test.format)
+		}
+	}
+}
@@ -4,8 +4,18 @@
 package qrcode
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestQRCodeMaxCapacity(t *testing.T) {
@@ -173,3 +183,335 @@ func BenchmarkQRCodeMaximumSize(b *testing.B) {
 		New(strings.Repeat("0", 7089), Low)
 	}
 }
+
+func BenchmarkEncodeVersion40(b *testing.B) {
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		q, err := New(strings.Repeat("0", 7089), Low)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+
+		q.encode()
+	}
+}
+
+func BenchmarkEncodeVersion40Parallel(b *testing.B) {
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		q, err := New(strings.Repeat("0", 7089), Low)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+
+		q.ParallelMasks = true
+		q.encode()
+	}
+}
+
+func TestParallelMasks(t *testing.T) {
+	for _, content := range []string{
+		"A",
+		"https://example.org",
+		strings.Repeat("https://example.org/", 20),
+		strings.Repeat("0", 7089),
+	} {
+		q, err := New(content, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		q.encode()
+
+		parallel, err := New(content, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		parallel.ParallelMasks = true
+		parallel.encode()
+
+		if parallel.mask != q.mask || !reflect.DeepEqual(parallel.Bitmap(), q.Bitmap()) {
+			t.Errorf("%.20q got mask %d in parallel, expected %d", content, parallel.mask, q.mask)
+		}
+	}
+}
+
+func TestImageTransparentBackground(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.BackgroundColor = color.Transparent
+
+	png, err := q.PNG(100)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(png))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("quiet zone alpha got %d, expected 0", a)
+	}
+
+	// Formats without an alpha channel composite over white.
+	var b bytes.Buffer
+	if err := q.Encode(&b, FormatPNG1Bit, 100); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	img, _, err = image.Decode(&b)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if isDark(img.At(0, 0)) {
+		t.Error("1-bit PNG quiet zone is dark, expected light")
+	}
+}
+
+func TestBeautifyImageTranslucentColors(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q.BackgroundColor = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	q.PixelColor = color.NRGBA{0, 0, 0, 0x80}
+	q.BoxColor = color.NRGBA{0, 0, 0, 0x80}
+
+	img := q.BeautifyImage(-4)
+
+	// The top left finder pattern starts after the 4 module quiet zone. The
+	// translucent black is blended with the white background.
+	r, g, b, a := img.At(16, 16).RGBA()
+	if a != 0xffff || r < 0x7e00 || r > 0x8100 || g != r || b != r {
+		t.Errorf("finder pattern got (%x,%x,%x,%x), expected 50%% gray", r, g, b, a)
+	}
+
+	q.BackgroundColor = color.Transparent
+	img = q.BeautifyImage(-4)
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("quiet zone alpha got %d, expected 0", a)
+	}
+
+	if _, _, _, a := img.At(16, 16).RGBA(); a < 0x7e00 || a > 0x8100 {
+		t.Errorf("finder pattern alpha got %x, expected 50%%", a)
+	}
+}
+
+func TestBeautifyImageLogoCompositing(t *testing.T) {
+	q, err := New("https://example.org", Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// An opaque white logo must be drawn as white: Not wrapped around by
+	// adding it to the pixels underneath.
+	var logo image.Image = rectangleImage(40, 40, color.White)
+	q.CenterLogo = &logo
+
+	img := q.BeautifyImage(256)
+
+	if r, g, b, _ := img.At(128, 128).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("logo center got (%x,%x,%x), expected white", r, g, b)
+	}
+}
+
+func TestLoadAndSetCenterLogo(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, rectangleImage(40, 40, red)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	path := filepath.Join(t.TempDir(), "logo.png")
+	if err := ioutil.WriteFile(path, encoded.Bytes(), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fsys := fstest.MapFS{"images/logo.png": {Data: encoded.Bytes()}}
+
+	tests := []struct {
+		name string
+		load func(q *QRCode) error
+	}{
+		{"path", func(q *QRCode) error { return q.LoadAndSetCenterLogo(path, 2) }},
+		{"reader", func(q *QRCode) error { return q.ReadAndSetCenterLogo(bytes.NewReader(encoded.Bytes()), 2) }},
+		{"fs", func(q *QRCode) error { return q.LoadAndSetCenterLogoFS(fsys, "images/logo.png", 2) }},
+	}
+
+	for _, test := range tests {
+		q, err := New("https://example.org", Highest)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// A previously drawn logo of the same size is replaced.
+		q.SetCenterLogo(rectangleImage(40, 40, color.White), 2)
+		q.BeautifyImage(256)
+
+		if err := test.load(q); err != nil {
+			t.Errorf("%s: got error %s", test.name, err.Error())
+			continue
+		}
+
+		img := q.BeautifyImage(256)
+		if c := color.RGBAModel.Convert(img.At(128, 128)); c != red {
+			t.Errorf("%s: logo center got %v, expected %v", test.name, c, red)
+		}
+	}
+}
+
+func TestLoadImageErrors(t *testing.T) {
+	q, err := New("https://example.org", Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fsys := fstest.MapFS{"logo.png": {Data: []byte("not an image")}}
+
+	if err := q.LoadAndSetCenterLogo(filepath.Join(t.TempDir(), "missing.png"), 0); err == nil {
+		t.Error("missing file got no error")
+	}
+	if err := q.LoadAndSetFinderPatternImageFS(fsys, "missing.png"); err == nil {
+		t.Error("missing fs file got no error")
+	}
+	if err := q.LoadAndSetAlignmentPatternImageFS(fsys, "logo.png"); err == nil {
+		t.Error("invalid image got no error")
+	}
+	if err := q.ReadAndSetBackgroundImage(strings.NewReader(""), BackgroundDots); err == nil {
+		t.Error("empty reader got no error")
+	}
+
+	if q.CenterLogo != nil || q.FinderPatternImage != nil || q.AlignmentPatternImage != nil || q.BackgroundImage != nil {
+		t.Error("image set despite error")
+	}
+}
+
+// beautifyImageTests are BeautifyImage options covering each drawing path.
+func beautifyImageTests() []struct {
+	name  string
+	size  int
+	setup func(q *QRCode)
+} {
+	picture := image.NewRGBA(image.Rect(0, 0, 97, 83))
+	for y := 0; y < 83; y++ {
+		for x := 0; x < 97; x++ {
+			picture.Set(x, y, color.NRGBA{uint8(x * 2), uint8(y * 3), uint8(x + y), uint8(128 + x)})
+		}
+	}
+
+	translucent := color.NRGBA{0x20, 0x40, 0x80, 0xa0}
+	gradient := &Gradient{Kind: RadialGradient, Stops: []GradientStop{{0, color.Black}, {1, translucent}}}
+
+	return []struct {
+		name  string
+		size  int
+		setup func(q *QRCode)
+	}{
+		{"plain", 1024, func(q *QRCode) {}},
+		{"variable", -7, func(q *QRCode) {}},
+		{"uneven", 301, func(q *QRCode) { q.DisableBorder = true }},
+		{"translucent", 512, func(q *QRCode) { q.PixelColor, q.BoxColor = translucent, translucent }},
+		{"logo", 512, func(q *QRCode) { q.SetCenterLogo(picture, 4) }},
+		{"logo style", 512, func(q *QRCode) {
+			q.SetCenterLogo(picture, 0)
+			q.LogoStyle = &LogoStyle{Shape: LogoCircle, Padding: 1}
+		}},
+		{"pattern images", 512, func(q *QRCode) {
+			q.SetFinderPatternImage(picture)
+			q.SetAlignmentPatternImage(picture)
+		}},
+		{"styles", 512, func(q *QRCode) {
+			q.FinderStyle = &EyeStyle{Outer: EyeRounded, Inner: EyeCircle}
+			q.AlignmentStyle = &EyeStyle{Outer: EyeCircle, InnerColor: color.Black}
+			q.DataGradient, q.FinderGradient = gradient, gradient
+			q.ModuleShape = LiquidShape
+		}},
+		{"background dots", 512, func(q *QRCode) { q.SetBackgroundImage(picture, BackgroundDots) }},
+		{"background adjust", 512, func(q *QRCode) {
+			q.SetBackgroundImage(picture, BackgroundAdjust)
+			q.ModuleShape = CircleShape
+		}},
+	}
+}
+
+func TestBeautifyImageGolden(t *testing.T) {
+	// Hashes of the images drawn before BeautifyImage was optimised: Each must
+	// be drawn exactly the same. The background photo cases changed since, to
+	// fill the function patterns without gaps and clear the quiet zone. The
+	// styles case changed by a level in some anti-aliased edge pixels, to draw
+	// LiquidShape's fillets clockwise. The styles and background dots cases
+	// changed again by up to two levels in anti-aliased edge pixels, now that
+	// each module shape is rasterized once to an 8-bit mask and stamped.
+	expected := map[string]string{
+		"plain":             "39c6ae6d9ab0287ac3cc7041a656d858bf0194f5389aed54e281ef9c2e38bd11",
+		"variable":          "20c0c69a7f886aa4de6e18268c7d5681a4bb4b3bdbec33ce0559bf7ee5358a90",
+		"uneven":            "dab06c5241f366a0223714b9264decc5fa6149fdb2a88d3a48d30db6721542ad",
+		"translucent":       "5f8072c35d2d6decebcdbdc5f93532f3550e27951a43583d3d1293936beb8bcd",
+		"logo":              "e24b37562dd3746237cc6ae09ab809bb53b5a265bfcc023c0e4c43a034158e2e",
+		"logo style":        "d29e2a5d9a2c556648c893a53dadd259804869741b6eb8a4176d95985c3ff088",
+		"pattern images":    "0ef36828d1d137c50ba8d4c75db491acd21345937a4257733b8f3f804a78c6ab",
+		"styles":            "1738723316a3c2d3756507aebb106e735f64823af03ec072e6b8ff2d2611dc31",
+		"background dots":   "3eeb5df97339675f0b34da5ea8a7e03fa0281dfb8d0b93bffac4eb1064e60b2e",
+		"background adjust": "949a2495092d514d1e37d3556993d2ac7c7500093f0c1ace1129d3b0ae325c9b",
+	}
+
+	for _, test := range beautifyImageTests() {
+		q, err := New(strings.Repeat("https://example.org/", 6), Medium)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		test.setup(q)
+
+		img := q.BeautifyImage(test.size).(*image.RGBA)
+		got := fmt.Sprintf("%x", sha256.Sum256(img.Pix))
+
+		if got != expected[test.name] {
+			t.Errorf("%s got image hash %s, expected %s", test.name, got, expected[test.name])
+		}
+	}
+}
+
+// BenchmarkBeautifyImage times drawing an encoded QR Code. Compared with the
+// per-pixel Set code BeautifyImage replaced, running this benchmark on both
+// (median ms/op of 7 interleaved runs, on one noisy core):
+//
+//	case               before   after  speedup
+//	plain               24.92    1.16      21x
+//	variable             3.57    0.23      16x
+//	uneven               3.27    0.21      16x
+//	translucent          7.63    0.43      18x
+//	logo                 7.22    0.57      13x
+//	logo style          10.80    0.56      19x
+//	pattern images       6.93    0.38      18x
+//	styles             191.34   10.39      18x
+//	background dots     17.68    1.27      14x
+//	background adjust   47.57    3.45      14x
+func BenchmarkBeautifyImage(b *testing.B) {
+	for _, test := range beautifyImageTests() {
+		b.Run(test.name, func(b *testing.B) {
+			q, err := New(strings.Repeat("https://example.org/", 6), Medium)
+			if err != nil {
+				b.Fatal(err.Error())
+			}
+			test.setup(q)
+
+			// Only the drawing is timed.
+			q.encode()
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				q.beautifyImage(test.size)
+			}
+		})
+	}
+}
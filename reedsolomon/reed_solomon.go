@@ -12,6 +12,7 @@ package reedsolomon
 
 import (
 	"log"
+	"sync"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
 )
@@ -23,36 +24,93 @@ import (
 //
 // ISO/IEC 18004 table 9 specifies the numECBytes required. e.g. a 1-L code has
 // numECBytes=7.
+//
+// The bytes of data are encoded by EncodeBytes. A final partial byte is read
+// as the value of its bits.
 func Encode(data *bitset.Bitset, numECBytes int) *bitset.Bitset {
-	// Create a polynomial representing |data|.
-	//
-	// The bytes are interpreted as the sequence of coefficients of a polynomial.
-	// The last byte's value becomes the x^0 coefficient, the second to last
-	// becomes the x^1 coefficient and so on.
-	ecpoly := newGFPolyFromData(data)
-	ecpoly = gfPolyMultiply(ecpoly, newGFPolyMonomial(gfOne, numECBytes))
-
-	// Pick the generator polynomial.
-	generator := rsGeneratorPoly(numECBytes)
-
-	// Generate the error correction bytes.
-	remainder := gfPolyRemainder(ecpoly, generator)
-
-	// Combine the data & error correcting bytes.
-	// The mathematically correct answer is:
-	//
-	//	result := gfPolyAdd(ecpoly, remainder).
-	//
-	// The encoding used by QR Code 2005 is slightly different this result: To
-	// preserve the original |data| bit sequence exactly, the data and remainder
-	// are combined manually below. This ensures any most significant zero bits
-	// are preserved (and not optimised away).
+	bytes := make([]byte, 0, (data.Len()+7)/8)
+	for i := 0; i < data.Len(); i += 8 {
+		bytes = append(bytes, data.ByteAt(i))
+	}
+
+	// The data and error correction bytes are combined here, rather than
+	// using the bytes EncodeBytes returns, to preserve the original |data| bit
+	// sequence exactly.
 	result := bitset.Clone(data)
-	result.AppendBytes(remainder.data(numECBytes))
+	result.AppendBytes(EncodeBytes(bytes, numECBytes)[len(bytes):])
+
+	return result
+}
+
+// EncodeBytes returns data with numECBytes error correction bytes appended,
+// using the appropriate Reed-Solomon code for QR Code 2005.
+//
+// The error correction bytes are the remainder of dividing the data, as a
+// polynomial, by the generator polynomial. The division is done a byte at a
+// time, as a linear feedback shift register holding the remainder, with the
+// multiplications by the generator's coefficients done in the log domain.
+func EncodeBytes(data []byte, numECBytes int) []byte {
+	generator := generatorLogs(numECBytes)
+
+	result := make([]byte, len(data)+numECBytes)
+	copy(result, data)
+
+	remainder := result[len(data):]
+	for _, d := range data {
+		feedback := gfElement(d ^ remainder[0])
+
+		copy(remainder, remainder[1:])
+		remainder[numECBytes-1] = 0
+
+		if feedback == gfZero {
+			continue
+		}
+
+		logFeedback := gfLogTable[feedback]
+		for i, logCoefficient := range generator {
+			if logCoefficient < 0 {
+				continue
+			}
+
+			remainder[i] ^= byte(gfExpTable[(logCoefficient+logFeedback)%255])
+		}
+	}
 
 	return result
 }
 
+// generators caches the generator polynomial of each degree used, in the form
+// returned by generatorLogs. QR Codes use only a few dozen degrees.
+var generators = struct {
+	sync.Mutex
+	logs map[int][]int
+}{logs: make(map[int][]int)}
+
+// generatorLogs returns the logs of the coefficients of the Reed-Solomon
+// generator polynomial with |degree|, from x^(degree-1) down to x^0. The
+// x^degree coefficient is always 1, and zero coefficients have a log of -1.
+//
+// The slice returned is shared, and must not be modified.
+func generatorLogs(degree int) []int {
+	generators.Lock()
+	defer generators.Unlock()
+
+	if logs, ok := generators.logs[degree]; ok {
+		return logs
+	}
+
+	generator := rsGeneratorPoly(degree)
+
+	logs := make([]int, degree)
+	for i := range logs {
+		logs[i] = gfLogTable[generator.term[degree-1-i]]
+	}
+
+	generators.logs[degree] = logs
+
+	return logs
+}
+
 // rsGeneratorPoly returns the Reed-Solomon generator polynomial with |degree|.
 //
 // The generator polynomial is calculated as:
@@ -4,6 +4,8 @@
 package reedsolomon
 
 import (
+	"bytes"
+	"math/rand"
 	"testing"
 
 	bitset "github.com/skip2/go-qrcode/bitset"
@@ -87,3 +89,41 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeBytes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for numECBytes := 2; numECBytes <= 68; numECBytes++ {
+		for i := 0; i < 10; i++ {
+			data := make([]byte, 1+r.Intn(150))
+			r.Read(data)
+
+			bits := bitset.New()
+			bits.AppendBytes(data)
+
+			// The remainder of the division of the polynomials.
+			poly := gfPolyMultiply(newGFPolyFromData(bits), newGFPolyMonomial(gfOne, numECBytes))
+			expected := append(append([]byte{}, data...),
+				gfPolyRemainder(poly, rsGeneratorPoly(numECBytes)).data(numECBytes)...)
+
+			result := EncodeBytes(data, numECBytes)
+			if !bytes.Equal(result, expected) {
+				t.Fatalf("data=%x, numECBytes=%d, encoded=%x, want %x",
+					data, numECBytes, result, expected)
+			}
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	data := bitset.New()
+	for i := 0; i < 118; i++ {
+		data.AppendByte(byte(i*37), 8)
+	}
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		Encode(data, 30)
+	}
+}
@@ -109,6 +109,18 @@ var (
 func buildRegularSymbol(version qrCodeVersion, mask int,
 	data *bitset.Bitset, includeQuietZone bool) (*symbol, error) {
 
+	m := newRegularSymbol(version, mask, data, includeQuietZone)
+
+	m.addData()
+
+	return m.symbol, nil
+}
+
+// newRegularSymbol returns a symbol with the function patterns added, ready
+// for the data to be added.
+func newRegularSymbol(version qrCodeVersion, mask int,
+	data *bitset.Bitset, includeQuietZone bool) *regularSymbol {
+
 	quietZoneSize := 0
 	if includeQuietZone {
 		quietZoneSize = version.quietZoneSize()
@@ -128,13 +140,9 @@ func buildRegularSymbol(version qrCodeVersion, mask int,
 	m.addTimingPatterns()
 	m.addFormatInfo()
 	m.addVersionInfo()
+	m.symbol.markFunctionPatterns()
 
-	ok, err := m.addData()
-	if !ok {
-		return nil, err
-	}
-
-	return m.symbol, nil
+	return m
 }
 
 func (m *regularSymbol) addFinderPatterns() {
@@ -188,14 +196,12 @@ func (m *regularSymbol) addAlignmentPatterns() {
 			}
 
 			m.symbol.set2dPattern(x-2, y-2, alignmentPattern)
+			m.symbol.set2dPatternForAlignment(x-2, y-2, alignmentPattern)
+			m.symbol.alignmentPatternPoints = append(m.symbol.alignmentPatternPoints, image.Point{x - 2, y - 2})
 		}
 	}
 
-	cent := alignmentPatternCenter[m.version.version]
-	x, y := cent[len(cent)-1], cent[len(cent)-1]
-	m.symbol.set2dPatternForLastAlignment(x-2, y-2, alignmentPattern)
 	m.symbol.alignmentPatternSize = len(alignmentPattern) // 5
-	m.symbol.alignmentPatternPoint = image.Point{x - 2, y - 2}
 }
 
 func (m *regularSymbol) addTimingPatterns() {
@@ -270,38 +276,51 @@ const (
 	down
 )
 
-func (m *regularSymbol) addData() (bool, error) {
+// addData adds the data, then applies the mask to all of the data modules at
+// once.
+func (m *regularSymbol) addData() {
+	m.addUnmaskedData()
+	m.symbol.module.xor(m.symbol.maskPattern(m.mask))
+}
+
+// addUnmaskedData adds the data without applying the mask.
+func (m *regularSymbol) addUnmaskedData() {
+	for i, p := range m.dataModulePositions(m.data.Len()) {
+		m.symbol.set(p.X, p.Y, m.data.At(i))
+	}
+}
+
+// withMask returns a copy of m with its data masked by mask, and the format
+// information for mask. m's data must be unmasked, and pattern is the mask
+// pattern returned by maskPattern(mask).
+func (m *regularSymbol) withMask(mask int, pattern bitMatrix) *regularSymbol {
+	masked := *m
+	masked.mask = mask
+	masked.symbol = m.symbol.clone()
+
+	masked.symbol.module.xor(pattern)
+	masked.addFormatInfo()
+
+	return &masked
+}
+
+// dataModulePositions returns the positions of the first n data modules, in
+// the order the data bits are placed: In two module wide columns, alternately
+// upwards and downwards from the bottom right, skipping over the function
+// patterns. The function patterns must already be added.
+func (m *regularSymbol) dataModulePositions(n int) []image.Point {
+	positions := make([]image.Point, 0, n)
+
 	xOffset := 1
 	dir := up
 
 	x := m.size - 2
 	y := m.size - 1
 
-	for i := 0; i < m.data.Len(); i++ {
-		var mask bool
-		switch m.mask {
-		case 0:
-			mask = (y+x+xOffset)%2 == 0
-		case 1:
-			mask = y%2 == 0
-		case 2:
-			mask = (x+xOffset)%3 == 0
-		case 3:
-			mask = (y+x+xOffset)%3 == 0
-		case 4:
-			mask = (y/2+(x+xOffset)/3)%2 == 0
-		case 5:
-			mask = (y*(x+xOffset))%2+(y*(x+xOffset))%3 == 0
-		case 6:
-			mask = ((y*(x+xOffset))%2+((y*(x+xOffset))%3))%2 == 0
-		case 7:
-			mask = ((y+x+xOffset)%2+((y*(x+xOffset))%3))%2 == 0
-		}
-
-		// != is equivalent to XOR.
-		m.symbol.set(x+xOffset, y, mask != m.data.At(i))
+	for i := 0; i < n; i++ {
+		positions = append(positions, image.Point{x + xOffset, y})
 
-		if i == m.data.Len()-1 {
+		if i == n-1 {
 			break
 		}
 
@@ -340,5 +359,29 @@ func (m *regularSymbol) addData() (bool, error) {
 		}
 	}
 
-	return true, nil
+	return positions
+}
+
+// dataMask returns true if the data module at (x, y) is inverted by mask.
+func dataMask(mask int, x int, y int) bool {
+	switch mask {
+	case 0:
+		return (y+x)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (y+x)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return (y*x)%2+(y*x)%3 == 0
+	case 6:
+		return ((y*x)%2+((y*x)%3))%2 == 0
+	case 7:
+		return ((y+x)%2+((y*x)%3))%2 == 0
+	}
+
+	return false
 }
@@ -29,3 +29,73 @@ func TestBuildRegularSymbol(t *testing.T) {
 		}
 	}
 }
+
+func TestRegularSymbolWithMask(t *testing.T) {
+	for _, version := range []int{1, 7, 40} {
+		v := getQRCodeVersion(Low, version)
+
+		data := bitset.New()
+		for i := 0; i < v.numDataBits()+8*v.numECCodewords()+v.numRemainderBits; i++ {
+			data.AppendBools(i%7 == 0 || i%5 == 1)
+		}
+
+		base := newRegularSymbol(*v, 0, data, true)
+		base.addUnmaskedData()
+
+		for mask := 0; mask < 8; mask++ {
+			// Function patterns, with the format information for mask.
+			function := newRegularSymbol(*v, mask, nil, true).symbol
+
+			s := base.withMask(mask, base.symbol.maskPattern(mask)).symbol
+			for y := 0; y < v.symbolSize(); y++ {
+				for x := 0; x < v.symbolSize(); x++ {
+					expected := base.symbol.get(x, y) != dataMask(mask, x, y)
+					if !function.empty(x, y) {
+						expected = function.get(x, y)
+					}
+
+					if s.get(x, y) != expected {
+						t.Fatalf("version %d mask %d (%d,%d) got %t, expected %t",
+							version, mask, x, y, !expected, expected)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAlignmentPatternPoints(t *testing.T) {
+	tests := []struct {
+		version  int
+		expected int
+	}{
+		{1, 0},
+		{2, 1},
+		{7, 6},
+		{14, 13},
+		{40, 46},
+	}
+
+	for _, test := range tests {
+		q, err := NewWithForcedVersion("example", test.version, Low)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		q.encode()
+
+		points := q.symbol.alignmentPatternPoints
+		if len(points) != test.expected {
+			t.Errorf("version %d got %d alignment patterns, expected %d",
+				test.version, len(points), test.expected)
+		}
+
+		// The dark centre of every alignment pattern is recorded.
+		bitmap := q.symbol.alignmentPatternBitmap()
+		border := q.symbol.borderSize()
+		for _, p := range points {
+			if !bitmap[p.Y+2+border][p.X+2+border] || bitmap[p.Y+1+border][p.X+2+border] {
+				t.Errorf("version %d alignment pattern at %v not in bitmap", test.version, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ScaleMode selects how Image maps image pixels to QR Code modules when a
+// fixed (positive) image size is requested.
+type ScaleMode int
+
+const (
+	// ScaleNearest maps each pixel to the nearest module. The symbol fills the
+	// whole image, but modules can be uneven widths (e.g. some 3px, some 4px).
+	ScaleNearest ScaleMode = iota
+
+	// ScaleInteger draws every module the same whole number of pixels wide,
+	// and centres the symbol in the image. The leftover pixels are added to
+	// the margin.
+	ScaleInteger
+
+	// ScaleSmooth draws modules with a fractional width, and anti-aliases
+	// module edges which fall part way through a pixel. The image is returned
+	// as an *image.RGBA rather than an *image.Paletted.
+	ScaleSmooth
+)
+
+// pixelLayout maps the pixels along one axis of a size*size image to the
+// modules of a realSize*realSize symbol (including the quiet zone).
+type pixelLayout struct {
+	// Width/height of the image.
+	size int
+
+	// Module index of each pixel, or -1 if the pixel is in the margin outside
+	// of the symbol.
+	module []int
+
+	// The pixels of each module, from start[m] up to end[m]. Both are zero
+	// for a module with no pixels.
+	start []int
+	end   []int
+}
+
+// newPixelLayout returns the pixel layout for mode. Smooth scaling has no
+// one-to-one pixel layout, and is laid out as ScaleInteger.
+func newPixelLayout(size int, realSize int, mode ScaleMode) *pixelLayout {
+	l := &pixelLayout{size: size, module: make([]int, size)}
+
+	switch mode {
+	case ScaleInteger, ScaleSmooth:
+		moduleSize := size / realSize
+		offset := (size - moduleSize*realSize) / 2
+
+		for p := range l.module {
+			l.module[p] = -1
+
+			if p >= offset && p < offset+moduleSize*realSize {
+				l.module[p] = (p - offset) / moduleSize
+			}
+		}
+	default:
+		// Map each image pixel to the nearest QR code module.
+		modulesPerPixel := float64(realSize) / float64(size)
+
+		for p := range l.module {
+			l.module[p] = int(float64(p) * modulesPerPixel)
+		}
+	}
+
+	l.start = make([]int, realSize)
+	l.end = make([]int, realSize)
+	for p := size - 1; p >= 0; p-- {
+		if m := l.module[p]; m >= 0 {
+			if l.end[m] == 0 {
+				l.end[m] = p + 1
+			}
+			l.start[m] = p
+		}
+	}
+
+	return l
+}
+
+// moduleRect returns the pixels of module (x, y), which tile the symbol
+// without gaps.
+func (l *pixelLayout) moduleRect(x int, y int) image.Rectangle {
+	return image.Rect(l.start[x], l.start[y], l.end[x], l.end[y])
+}
+
+// smoothImage returns the QR Code as a size*size image, with fractional width
+// modules and anti-aliased module edges.
+//
+// Each pixel is coloured by the area weighted average of the (premultiplied)
+// colours of the modules it covers.
+func (q *QRCode) smoothImage(size int) *image.RGBA {
+	realSize := q.symbol.size
+	modulesPerPixel := float64(realSize) / float64(size)
+
+	bitmap := q.symbol.bitmap()
+	boxes := q.symbol.finderPatternBitmap()
+
+	var palette [3][4]float64
+	for i, c := range []color.Color{q.BackgroundColor, q.PixelColor, q.BoxColor} {
+		r, g, b, a := c.RGBA()
+		palette[i] = [4]float64{float64(r), float64(g), float64(b), float64(a)}
+	}
+
+	// colorIndex returns the palette index of the module at (x, y).
+	colorIndex := func(x, y int) int {
+		switch {
+		case boxes[y][x]:
+			return 2
+		case bitmap[y][x]:
+			return 1
+		}
+
+		return 0
+	}
+
+	// The (upto two) modules covered by each pixel along an axis, and the
+	// proportion of the pixel covered by the first.
+	first := make([]int, size)
+	weight := make([]float64, size)
+	for p := 0; p < size; p++ {
+		start := float64(p) * modulesPerPixel
+		end := float64(p+1) * modulesPerPixel
+
+		first[p] = int(start)
+		weight[p] = 1
+
+		if boundary := math.Floor(end); boundary > start && boundary < end && int(boundary) < realSize {
+			weight[p] = (boundary - start) / modulesPerPixel
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var sum [4]float64
+
+			for j := 0; j < 2; j++ {
+				wy := weight[y]
+				if j == 1 {
+					wy = 1 - wy
+				}
+				if wy == 0 {
+					continue
+				}
+
+				for i := 0; i < 2; i++ {
+					wx := weight[x]
+					if i == 1 {
+						wx = 1 - wx
+					}
+					if wx == 0 {
+						continue
+					}
+
+					c := palette[colorIndex(first[x]+i, first[y]+j)]
+					for k := range sum {
+						sum[k] += wx * wy * c[k]
+					}
+				}
+			}
+
+			pos := img.PixOffset(x, y)
+			for k := range sum {
+				img.Pix[pos+k] = uint8(math.Round(sum[k] / 0x101))
+			}
+		}
+	}
+
+	return img
+}
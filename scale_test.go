@@ -0,0 +1,128 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScaleNearest(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const size = 100
+	img := q.Image(size).(*image.Paletted)
+
+	bitmap := q.Bitmap()
+	modulesPerPixel := float64(len(bitmap)) / float64(size)
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := bitmap[int(float64(y)*modulesPerPixel)][int(float64(x)*modulesPerPixel)]
+
+			if isDark(img.At(x, y)) != v {
+				t.Fatalf("pixel (%d,%d) got dark=%t, expected %t", x, y, !v, v)
+			}
+		}
+	}
+}
+
+func TestScaleInteger(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.Scaling = ScaleInteger
+
+	// 33 modules in 100px: 3px modules, with the 1px leftover added to the
+	// right/bottom margin.
+	const size = 100
+	img := q.Image(size)
+
+	if img.Bounds().Dx() != size {
+		t.Fatalf("got size %d, expected %d", img.Bounds().Dx(), size)
+	}
+
+	bitmap := q.Bitmap()
+	realSize := len(bitmap)
+	moduleSize := size / realSize
+	offset := (size - moduleSize*realSize) / 2
+
+	if moduleSize != 3 || offset != 0 {
+		t.Fatalf("got module size %d offset %d, expected 3 and 0", moduleSize, offset)
+	}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			expected := false
+			if x >= offset && y >= offset && x < offset+moduleSize*realSize && y < offset+moduleSize*realSize {
+				expected = bitmap[(y-offset)/moduleSize][(x-offset)/moduleSize]
+			}
+
+			if isDark(img.At(x, y)) != expected {
+				t.Fatalf("pixel (%d,%d) got dark=%t, expected %t", x, y, !expected, expected)
+			}
+		}
+	}
+
+	// Every module is the same width, so every run of dark pixels along a row
+	// is a multiple of the module size.
+	for y := 0; y < size; y++ {
+		run := 0
+		for x := 0; x <= size; x++ {
+			if x < size && isDark(img.At(x, y)) {
+				run++
+				continue
+			}
+
+			if run%moduleSize != 0 {
+				t.Fatalf("row %d has a %dpx run, expected a multiple of %dpx", y, run, moduleSize)
+			}
+			run = 0
+		}
+	}
+}
+
+func TestScaleSmooth(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.Scaling = ScaleSmooth
+
+	// 33 modules in 50px: each module is ~1.5px.
+	const size = 50
+	img, ok := q.Image(size).(*image.RGBA)
+	if !ok {
+		t.Fatalf("got %T, expected *image.RGBA", q.Image(size))
+	}
+
+	var numBlack, numWhite, numGray int
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			switch img.RGBAAt(x, y) {
+			case color.RGBA{0, 0, 0, 0xff}:
+				numBlack++
+			case color.RGBA{0xff, 0xff, 0xff, 0xff}:
+				numWhite++
+			default:
+				numGray++
+			}
+		}
+	}
+
+	if numBlack == 0 || numWhite == 0 || numGray == 0 {
+		t.Errorf("got %d black, %d white, %d anti-aliased pixels, expected some of each",
+			numBlack, numWhite, numGray)
+	}
+
+	// The top left pixel is in the quiet zone.
+	if c := img.RGBAAt(0, 0); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("quiet zone pixel got %v, expected white", c)
+	}
+}
@@ -0,0 +1,753 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+)
+
+// A Path is a vector outline, made of one or more closed subpaths.
+//
+// Paths are drawn by both the raster (e.g. BeautifyImage) and vector (e.g.
+// SVG) renderers. Overlapping subpaths drawn in the same direction are merged,
+// subpaths drawn in the opposite direction cut holes. The helpers (AddRect,
+// AddCircle etc) all draw clockwise, with the y axis pointing down.
+type Path struct {
+	ops []pathOp
+}
+
+type pathOpType uint8
+
+const (
+	pathMoveTo pathOpType = iota
+	pathLineTo
+	pathQuadTo
+	pathCubeTo
+	pathClose
+)
+
+// pathOp is a single path drawing operation. Only the first 1 (MoveTo,
+// LineTo), 2 (QuadTo) or 3 (CubeTo) points are used.
+type pathOp struct {
+	op  pathOpType
+	pts [3][2]float64
+}
+
+// circleKappa is the distance of the control points from the ends of a cubic
+// Bézier curve approximating a quarter circle, relative to the radius.
+const circleKappa = 0.5522847498
+
+// MoveTo starts a new subpath at (x, y).
+func (p *Path) MoveTo(x, y float64) {
+	p.ops = append(p.ops, pathOp{op: pathMoveTo, pts: [3][2]float64{{x, y}}})
+}
+
+// LineTo adds a straight line to (x, y).
+func (p *Path) LineTo(x, y float64) {
+	p.ops = append(p.ops, pathOp{op: pathLineTo, pts: [3][2]float64{{x, y}}})
+}
+
+// QuadTo adds a quadratic Bézier curve with control point (cx, cy), ending at
+// (x, y).
+func (p *Path) QuadTo(cx, cy, x, y float64) {
+	p.ops = append(p.ops, pathOp{op: pathQuadTo, pts: [3][2]float64{{cx, cy}, {x, y}}})
+}
+
+// CubeTo adds a cubic Bézier curve with control points (c1x, c1y) and (c2x,
+// c2y), ending at (x, y).
+func (p *Path) CubeTo(c1x, c1y, c2x, c2y, x, y float64) {
+	p.ops = append(p.ops, pathOp{op: pathCubeTo, pts: [3][2]float64{{c1x, c1y}, {c2x, c2y}, {x, y}}})
+}
+
+// Close closes the current subpath.
+func (p *Path) Close() {
+	p.ops = append(p.ops, pathOp{op: pathClose})
+}
+
+// Empty returns true if nothing has been added to the path.
+func (p *Path) Empty() bool {
+	return len(p.ops) == 0
+}
+
+// AddRect adds a w*h rectangle with top left corner (x, y).
+func (p *Path) AddRect(x, y, w, h float64) {
+	p.MoveTo(x, y)
+	p.LineTo(x+w, y)
+	p.LineTo(x+w, y+h)
+	p.LineTo(x, y+h)
+	p.Close()
+}
+
+// AddRoundedRect adds a w*h rectangle with top left corner (x, y) and rounded
+// corners. The corner radii are given clockwise from the top left: top left,
+// top right, bottom right, bottom left.
+func (p *Path) AddRoundedRect(x, y, w, h float64, r [4]float64) {
+	k := 1 - circleKappa
+
+	p.MoveTo(x+r[0], y)
+	p.LineTo(x+w-r[1], y)
+	if r[1] > 0 {
+		p.CubeTo(x+w-r[1]*k, y, x+w, y+r[1]*k, x+w, y+r[1])
+	}
+	p.LineTo(x+w, y+h-r[2])
+	if r[2] > 0 {
+		p.CubeTo(x+w, y+h-r[2]*k, x+w-r[2]*k, y+h, x+w-r[2], y+h)
+	}
+	p.LineTo(x+r[3], y+h)
+	if r[3] > 0 {
+		p.CubeTo(x+r[3]*k, y+h, x, y+h-r[3]*k, x, y+h-r[3])
+	}
+	p.LineTo(x, y+r[0])
+	if r[0] > 0 {
+		p.CubeTo(x, y+r[0]*k, x+r[0]*k, y, x+r[0], y)
+	}
+	p.Close()
+}
+
+// AddCircle adds a circle centred on (cx, cy) with radius r.
+func (p *Path) AddCircle(cx, cy, r float64) {
+	p.AddRoundedRect(cx-r, cy-r, 2*r, 2*r, [4]float64{r, r, r, r})
+}
+
+// AddPolygon adds a closed polygon through the points given as x, y pairs.
+func (p *Path) AddPolygon(xy ...float64) {
+	for i := 0; i+1 < len(xy); i += 2 {
+		if i == 0 {
+			p.MoveTo(xy[i], xy[i+1])
+		} else {
+			p.LineTo(xy[i], xy[i+1])
+		}
+	}
+	p.Close()
+}
+
+// Append adds all of the subpaths of other.
+func (p *Path) Append(other *Path) {
+	p.ops = append(p.ops, other.ops...)
+}
+
+// transformed returns the path scaled by s, then translated by (dx, dy).
+func (p *Path) transformed(s, dx, dy float64) *Path {
+	result := &Path{ops: make([]pathOp, len(p.ops))}
+
+	for i, o := range p.ops {
+		for j := 0; j < numPathOpPoints(o.op); j++ {
+			o.pts[j] = [2]float64{o.pts[j][0]*s + dx, o.pts[j][1]*s + dy}
+		}
+		result.ops[i] = o
+	}
+
+	return result
+}
+
+// reversed returns the path with every subpath drawn in the opposite
+// direction, for cutting holes in other subpaths.
+func (p *Path) reversed() *Path {
+	result := &Path{}
+
+	start := 0
+	for start < len(p.ops) {
+		end := start + 1
+		for end < len(p.ops) && p.ops[end].op != pathMoveTo {
+			end++
+		}
+		result.appendReversedSubpath(p.ops[start:end])
+		start = end
+	}
+
+	return result
+}
+
+// appendReversedSubpath appends ops (a single subpath, beginning with a
+// MoveTo) drawn backwards.
+func (p *Path) appendReversedSubpath(ops []pathOp) {
+	// The end point of each op.
+	end := func(o pathOp) [2]float64 {
+		switch o.op {
+		case pathQuadTo:
+			return o.pts[1]
+		case pathCubeTo:
+			return o.pts[2]
+		}
+		return o.pts[0]
+	}
+
+	closed := false
+	for len(ops) > 0 && ops[len(ops)-1].op == pathClose {
+		closed = true
+		ops = ops[:len(ops)-1]
+	}
+
+	if len(ops) == 0 {
+		return
+	}
+
+	last := end(ops[len(ops)-1])
+	p.MoveTo(last[0], last[1])
+
+	for i := len(ops) - 1; i > 0; i-- {
+		o := ops[i]
+		prev := end(ops[i-1])
+
+		switch o.op {
+		case pathLineTo:
+			p.LineTo(prev[0], prev[1])
+		case pathQuadTo:
+			p.QuadTo(o.pts[0][0], o.pts[0][1], prev[0], prev[1])
+		case pathCubeTo:
+			p.CubeTo(o.pts[1][0], o.pts[1][1], o.pts[0][0], o.pts[0][1], prev[0], prev[1])
+		}
+	}
+
+	if closed {
+		p.Close()
+	}
+}
+
+// draw composites src over dst, through the area covered by the path.
+func (p *Path) draw(dst draw.Image, src image.Image) {
+	l := newMaskLayer(dst.Bounds())
+	l.add(p)
+	l.draw(dst, src)
+}
+
+// bounds returns the bounding box of the path's points, including the control
+// points of curves, which contains its outline.
+func (p *Path) bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+
+	for _, o := range p.ops {
+		for i := 0; i < numPathOpPoints(o.op); i++ {
+			minX, maxX = math.Min(minX, o.pts[i][0]), math.Max(maxX, o.pts[i][0])
+			minY, maxY = math.Min(minY, o.pts[i][1]), math.Max(maxY, o.pts[i][1])
+		}
+	}
+
+	return minX, minY, maxX, maxY
+}
+
+// A maskLayer collects the coverage of paths drawn with the same source, to
+// composite them onto an image at once through a single mask. Coverage adds
+// up where paths overlap.
+//
+// Each path is rasterized on its own, in its bounding box. Modules are
+// rasterized once for each size and set of neighbours, and their coverage
+// stamped at each module.
+type maskLayer struct {
+	bounds image.Rectangle
+
+	// Area covered so far.
+	covered image.Rectangle
+
+	// The stamps added, and where. Unless they overlap, they are drawn one by
+	// one, without building the layer's mask.
+	placed []placedStamp
+
+	// The pixels covered by the stamps, one bit each, until two overlap.
+	occupied   []uint64
+	overlapped bool
+
+	// The modules added, positioned relative to the pixel containing the
+	// module's top left corner.
+	modules map[moduleStampKey]*stamp
+
+	// The module added last. Neighbouring modules are often alike.
+	lastKey    moduleStampKey
+	lastModule *stamp
+}
+
+// A stamp is the coverage of a path, to be added to a layer at any offset.
+type stamp struct {
+	coverage *image.Alpha
+
+	// The pixels covered, one bit each, in rows of words.
+	bits  []uint64
+	words int
+}
+
+type placedStamp struct {
+	*stamp
+	offset image.Point
+}
+
+// moduleStampKey identifies modules drawn the same, apart from their position
+// in whole pixels.
+type moduleStampKey struct {
+	fx, fy, size float64
+	n            Neighbors
+}
+
+// newStamp returns a stamp of coverage.
+func newStamp(coverage *image.Alpha) *stamp {
+	b := coverage.Rect
+	s := &stamp{coverage: coverage, words: (b.Dx() + 63) / 64}
+	s.bits = make([]uint64, s.words*b.Dy())
+
+	for y := 0; y < b.Dy(); y++ {
+		bits := s.bits[y*s.words:]
+		for x, a := range coverage.Pix[y*coverage.Stride : y*coverage.Stride+b.Dx()] {
+			if a != 0 {
+				bits[x/64] |= 1 << uint(x%64)
+			}
+		}
+	}
+
+	return s
+}
+
+// newMaskLayer returns an empty layer, to draw onto an image with the given
+// bounds.
+func newMaskLayer(bounds image.Rectangle) *maskLayer {
+	return &maskLayer{bounds: bounds}
+}
+
+// add adds the area covered by p to the layer.
+func (l *maskLayer) add(p *Path) {
+	if p.Empty() {
+		return
+	}
+
+	l.stamp(newStamp(p.rasterize()), image.Point{})
+}
+
+// addModule adds a module drawn with shape, as by shape.AddModule. All the
+// modules of a layer must be drawn with the same shape.
+func (l *maskLayer) addModule(shape ModuleShape, x, y, size float64, n Neighbors) {
+	fx, fy := math.Floor(x), math.Floor(y)
+	key := moduleStampKey{x - fx, y - fy, size, n}
+
+	s, found := l.lastModule, l.lastModule != nil && l.lastKey == key
+	if !found {
+		s, found = l.modules[key]
+	}
+	if !found {
+		var p Path
+		shape.AddModule(&p, key.fx, key.fy, size, n)
+		if p.Empty() {
+			return
+		}
+
+		s = newStamp(p.rasterize())
+		if l.modules == nil {
+			l.modules = make(map[moduleStampKey]*stamp)
+		}
+		l.modules[key] = s
+	}
+	l.lastKey, l.lastModule = key, s
+
+	l.stamp(s, image.Pt(int(fx), int(fy)))
+}
+
+// stamp adds s, moved by offset, to the layer.
+func (l *maskLayer) stamp(s *stamp, offset image.Point) {
+	placed := s.coverage.Rect.Add(offset)
+
+	r := placed.Intersect(l.bounds)
+	if r.Empty() {
+		return
+	}
+	l.covered = l.covered.Union(r)
+	l.placed = append(l.placed, placedStamp{s, offset})
+
+	if l.overlapped {
+		return
+	}
+	if r != placed {
+		// Stamps cut off by the edge of the layer are left to the mask.
+		l.overlapped = true
+		return
+	}
+	if l.occupied == nil {
+		l.occupied = make([]uint64, (l.bounds.Dx()*l.bounds.Dy()+63)/64+1)
+	}
+
+	// Shift each row of the stamp's bits into place over the occupied words.
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := (y-l.bounds.Min.Y)*l.bounds.Dx() + r.Min.X - l.bounds.Min.X
+		word, shift := i/64, uint(i%64)
+
+		for k, bits := range s.bits[(y-r.Min.Y)*s.words : (y-r.Min.Y+1)*s.words] {
+			low, high := bits<<shift, bits>>(64-shift)
+			if l.occupied[word+k]&low != 0 || l.occupied[word+k+1]&high != 0 {
+				l.overlapped = true
+				return
+			}
+			l.occupied[word+k] |= low
+			l.occupied[word+k+1] |= high
+		}
+	}
+}
+
+// moved returns the coverage of p, moved into place.
+func (p placedStamp) moved() *image.Alpha {
+	moved := *p.coverage
+	moved.Rect = moved.Rect.Add(p.offset)
+
+	return &moved
+}
+
+// mask returns the layer's mask over the area covered, adding up the stamps'
+// coverage.
+func (l *maskLayer) mask() *image.Alpha {
+	mask := image.NewAlpha(l.covered)
+
+	for _, p := range l.placed {
+		src := p.moved()
+		r := src.Rect.Intersect(l.covered)
+
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			i := mask.PixOffset(r.Min.X, y)
+			dst := mask.Pix[i : i+r.Dx()]
+			row := src.Pix[src.PixOffset(r.Min.X, y):]
+
+			for x, a := range dst {
+				if c := uint32(a) + uint32(row[x]); c < 0xff {
+					dst[x] = uint8(c)
+				} else {
+					dst[x] = 0xff
+				}
+			}
+		}
+	}
+
+	return mask
+}
+
+// rasterize returns the coverage of p, over the pixels containing it.
+func (p *Path) rasterize() *image.Alpha {
+	minX, minY, maxX, maxY := p.bounds()
+	origin := image.Pt(int(math.Floor(minX)), int(math.Floor(minY)))
+
+	w, h := int(math.Ceil(maxX))-origin.X, int(math.Ceil(maxY))-origin.Y
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	z := vector.NewRasterizer(w, h)
+
+	f := func(v float64, min int) float32 {
+		return float32(v - float64(min))
+	}
+
+	for _, o := range p.ops {
+		switch o.op {
+		case pathMoveTo:
+			z.MoveTo(f(o.pts[0][0], origin.X), f(o.pts[0][1], origin.Y))
+		case pathLineTo:
+			z.LineTo(f(o.pts[0][0], origin.X), f(o.pts[0][1], origin.Y))
+		case pathQuadTo:
+			z.QuadTo(f(o.pts[0][0], origin.X), f(o.pts[0][1], origin.Y),
+				f(o.pts[1][0], origin.X), f(o.pts[1][1], origin.Y))
+		case pathCubeTo:
+			z.CubeTo(f(o.pts[0][0], origin.X), f(o.pts[0][1], origin.Y),
+				f(o.pts[1][0], origin.X), f(o.pts[1][1], origin.Y),
+				f(o.pts[2][0], origin.X), f(o.pts[2][1], origin.Y))
+		case pathClose:
+			z.ClosePath()
+		}
+	}
+
+	coverage := image.NewAlpha(image.Rect(0, 0, w, h))
+	z.DrawOp = draw.Src
+	z.Draw(coverage, coverage.Rect, image.Opaque, image.Point{})
+	coverage.Rect = coverage.Rect.Add(origin)
+
+	return coverage
+}
+
+// draw composites src over dst through the layer's mask. src is in the same
+// coordinate space as dst.
+func (l *maskLayer) draw(dst draw.Image, src image.Image) {
+	r := l.covered
+	if r.Empty() {
+		return
+	}
+
+	// draw.DrawMask reads sources other than colours, such as gradients, at
+	// every pixel, and blends even an opaque colour into fully covered
+	// pixels. drawMaskOver does neither.
+	rgba, ok := dst.(*image.RGBA)
+	switch {
+	case ok && !l.overlapped:
+		for _, p := range l.placed {
+			moved := p.moved()
+			drawMaskOver(rgba, moved.Rect, moved, src)
+		}
+	case ok:
+		drawMaskOver(rgba, r, l.mask(), src)
+	default:
+		draw.DrawMask(dst, r, src, r.Min, l.mask(), r.Min, draw.Over)
+	}
+}
+
+// drawMaskOver composites src over the rectangle r of dst through mask, with
+// the same arithmetic as draw.DrawMask. mask and src are in the same
+// coordinate space as dst.
+func drawMaskOver(dst *image.RGBA, r image.Rectangle, mask *image.Alpha, src image.Image) {
+	uniform, isUniform := src.(*image.Uniform)
+
+	var sr, sg, sb, sa uint32
+	if isUniform {
+		sr, sg, sb, sa = uniform.C.RGBA()
+	}
+	solid := [4]uint8{uint8(sr >> 8), uint8(sg >> 8), uint8(sb >> 8), uint8(sa >> 8)}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := mask.Pix[mask.PixOffset(r.Min.X, y):]
+		pixels := dst.Pix[dst.PixOffset(r.Min.X, y):]
+
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ma := uint32(row[x-r.Min.X])
+			if ma == 0 {
+				continue
+			}
+
+			i := (x - r.Min.X) * 4
+			p := pixels[i : i+4 : i+4]
+
+			if !isUniform {
+				sr, sg, sb, sa = sourceColor(src, x, y)
+			} else if ma == 0xff && sa == 0xffff {
+				// Opaque, fully covered pixels are just the colour.
+				copy(p, solid[:])
+				continue
+			}
+
+			ma |= ma << 8
+			a := (0xffff - (sa * ma / 0xffff)) * 0x101
+			p[0] = uint8((uint32(p[0])*a + sr*ma) / 0xffff >> 8)
+			p[1] = uint8((uint32(p[1])*a + sg*ma) / 0xffff >> 8)
+			p[2] = uint8((uint32(p[2])*a + sb*ma) / 0xffff >> 8)
+			p[3] = uint8((uint32(p[3])*a + sa*ma) / 0xffff >> 8)
+		}
+	}
+}
+
+// fill fills the path onto dst with the colour c.
+func (p *Path) fill(dst draw.Image, c color.Color) {
+	p.draw(dst, image.NewUniform(c))
+}
+
+// svgData returns the path as SVG path data (the d attribute of a path
+// element).
+func (p *Path) svgData() string {
+	var b strings.Builder
+
+	for _, o := range p.ops {
+		switch o.op {
+		case pathMoveTo:
+			b.WriteByte('M')
+		case pathLineTo:
+			b.WriteByte('L')
+		case pathQuadTo:
+			b.WriteByte('Q')
+		case pathCubeTo:
+			b.WriteByte('C')
+		case pathClose:
+			b.WriteByte('Z')
+			continue
+		}
+
+		for i := 0; i < numPathOpPoints(o.op); i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(formatCoordinate(o.pts[i][0]))
+			b.WriteByte(' ')
+			b.WriteString(formatCoordinate(o.pts[i][1]))
+		}
+	}
+
+	return b.String()
+}
+
+// numPathOpPoints returns the number of points used by an op.
+func numPathOpPoints(op pathOpType) int {
+	switch op {
+	case pathMoveTo, pathLineTo:
+		return 1
+	case pathQuadTo:
+		return 2
+	case pathCubeTo:
+		return 3
+	}
+
+	return 0
+}
+
+// formatCoordinate formats v rounded to 3 decimal places, with trailing zeros
+// removed.
+func formatCoordinate(v float64) string {
+	v = math.Round(v*1000) / 1000
+	if v == 0 {
+		// Avoid "-0".
+		v = 0
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Neighbors records which of the eight modules around a module are set
+// (dark).
+type Neighbors uint8
+
+// The neighbouring modules, by compass direction (north is up).
+const (
+	NeighborN Neighbors = 1 << iota
+	NeighborNE
+	NeighborE
+	NeighborSE
+	NeighborS
+	NeighborSW
+	NeighborW
+	NeighborNW
+)
+
+// Has returns true if all of the neighbours in d are set.
+func (n Neighbors) Has(d Neighbors) bool {
+	return n&d == d
+}
+
+// neighbors returns the neighbours of the module at bitmap[y][x] which are
+// set. Modules outside of the bitmap are unset.
+func neighbors(bitmap [][]bool, x, y int) Neighbors {
+	offsets := [8]struct {
+		dx, dy int
+	}{
+		{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1},
+	}
+
+	var n Neighbors
+	for i, o := range offsets {
+		x2, y2 := x+o.dx, y+o.dy
+
+		if y2 >= 0 && y2 < len(bitmap) && x2 >= 0 && x2 < len(bitmap[y2]) && bitmap[y2][x2] {
+			n |= 1 << uint(i)
+		}
+	}
+
+	return n
+}
+
+// A ModuleShape decides how each dark data module is drawn.
+//
+// AddModule adds the outline of the module occupying the size*size square with
+// top left corner (x, y) to p. The module's set neighbours are given by n, so
+// shapes can join up with (or avoid) the modules around them. Outlines may
+// extend beyond the module's square, and should be drawn clockwise.
+//
+// An outline must depend only on the module's size, its neighbours and its
+// position relative to the pixel grid: BeautifyImage outlines each of these
+// once, and copies the result to every module like it.
+//
+// Module shapes apply to data and error correction modules only: The finder,
+// alignment and timing patterns and the format and version information are
+// always drawn as squares, to keep them reliably scannable.
+type ModuleShape interface {
+	AddModule(p *Path, x, y, size float64, n Neighbors)
+}
+
+// ModuleShapeFunc adapts a function to the ModuleShape interface.
+type ModuleShapeFunc func(p *Path, x, y, size float64, n Neighbors)
+
+// AddModule calls f(p, x, y, size, n).
+func (f ModuleShapeFunc) AddModule(p *Path, x, y, size float64, n Neighbors) {
+	f(p, x, y, size, n)
+}
+
+// SquareShape draws each module as a square. This is the default shape.
+var SquareShape ModuleShape = ModuleShapeFunc(func(p *Path, x, y, size float64, n Neighbors) {
+	p.AddRect(x, y, size, size)
+})
+
+// CircleShape draws each module as a circular dot.
+var CircleShape ModuleShape = ModuleShapeFunc(func(p *Path, x, y, size float64, n Neighbors) {
+	p.AddCircle(x+size/2, y+size/2, size/2)
+})
+
+// DiamondShape draws each module as a diamond (a square rotated by 45
+// degrees).
+var DiamondShape ModuleShape = ModuleShapeFunc(func(p *Path, x, y, size float64, n Neighbors) {
+	p.AddPolygon(x+size/2, y, x+size, y+size/2, x+size/2, y+size, x, y+size/2)
+})
+
+// RoundedShape draws each module as a square with rounded corners. Radius is
+// the corner radius relative to the module size, from 0 (square) to 0.5
+// (circle).
+type RoundedShape struct {
+	Radius float64
+}
+
+// AddModule adds a rounded square.
+func (s RoundedShape) AddModule(p *Path, x, y, size float64, n Neighbors) {
+	r := math.Max(0, math.Min(s.Radius, 0.5)) * size
+
+	p.AddRoundedRect(x, y, size, size, [4]float64{r, r, r, r})
+}
+
+// LiquidShape draws modules as connected blobs: Neighbouring dark modules
+// merge together, outside corners are rounded, and inside corners are filled
+// with a curve.
+var LiquidShape ModuleShape = ModuleShapeFunc(func(p *Path, x, y, size float64, n Neighbors) {
+	r := size / 2
+
+	// Round each corner which has no orthogonal neighbours.
+	var radii [4]float64
+	for i, d := range [4]Neighbors{
+		NeighborN | NeighborW,
+		NeighborN | NeighborE,
+		NeighborS | NeighborE,
+		NeighborS | NeighborW,
+	} {
+		if n&d == 0 {
+			radii[i] = r
+		}
+	}
+	p.AddRoundedRect(x, y, size, size, radii)
+
+	// Fill inside corners: Where this module and two orthogonal neighbours
+	// surround an unset diagonal neighbour, add a concave fillet in that
+	// corner of the diagonal module. Only this module sees both neighbours
+	// set, so each fillet is added once.
+	fillet := func(cx, cy, sx, sy float64) {
+		// (cx, cy) is the corner, (sx, sy) the direction into the unset
+		// module. The horizontal edge comes first for the corners where
+		// that is clockwise (sx*sy > 0), the vertical edge for the others.
+		k := 1 - circleKappa
+		fr := r / 2
+		p.MoveTo(cx, cy)
+		if sx*sy > 0 {
+			p.LineTo(cx+sx*fr, cy)
+			p.CubeTo(cx+sx*fr*k, cy, cx, cy+sy*fr*k, cx, cy+sy*fr)
+		} else {
+			p.LineTo(cx, cy+sy*fr)
+			p.CubeTo(cx, cy+sy*fr*k, cx+sx*fr*k, cy, cx+sx*fr, cy)
+		}
+		p.Close()
+	}
+
+	if n.Has(NeighborN|NeighborE) && !n.Has(NeighborNE) {
+		fillet(x+size, y, 1, -1)
+	}
+	if n.Has(NeighborS|NeighborE) && !n.Has(NeighborSE) {
+		fillet(x+size, y+size, 1, 1)
+	}
+	if n.Has(NeighborN|NeighborW) && !n.Has(NeighborNW) {
+		fillet(x, y, -1, -1)
+	}
+	if n.Has(NeighborS|NeighborW) && !n.Has(NeighborSW) {
+		fillet(x, y+size, -1, 1)
+	}
+})
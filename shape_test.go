@@ -0,0 +1,310 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestNeighbors(t *testing.T) {
+	bitmap := [][]bool{
+		{b1, b0, b1},
+		{b0, b1, b1},
+		{b0, b0, b0},
+	}
+
+	n := neighbors(bitmap, 1, 1)
+	if n != NeighborNW|NeighborNE|NeighborE {
+		t.Errorf("got %08b, expected %08b", n, NeighborNW|NeighborNE|NeighborE)
+	}
+
+	if !n.Has(NeighborNE|NeighborE) || n.Has(NeighborN|NeighborE) {
+		t.Error("Has got wrong result")
+	}
+
+	// Modules outside the bitmap are unset.
+	if n := neighbors(bitmap, 0, 0); n != NeighborSE {
+		t.Errorf("got %08b, expected %08b", n, NeighborSE)
+	}
+}
+
+// coverage returns the proportion of img which is opaque black.
+func coverage(img *image.RGBA) float64 {
+	var sum float64
+	for i := 3; i < len(img.Pix); i += 4 {
+		sum += float64(img.Pix[i]) / 0xff
+	}
+
+	return sum / float64(img.Bounds().Dx()*img.Bounds().Dy())
+}
+
+func TestPathFill(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	var p Path
+	p.AddRect(2, 2, 3, 3)
+	p.fill(img, color.Black)
+
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			inside := x >= 2 && x < 5 && y >= 2 && y < 5
+			if _, _, _, a := img.At(x, y).RGBA(); (a == 0xffff) != inside || (a != 0 && !inside) {
+				t.Fatalf("pixel (%d,%d) got alpha %x, expected inside=%t", x, y, a, inside)
+			}
+		}
+	}
+
+	img = image.NewRGBA(image.Rect(0, 0, 100, 100))
+	p = Path{}
+	p.AddCircle(50, 50, 50)
+	p.fill(img, color.Black)
+
+	if c := coverage(img); math.Abs(c-math.Pi/4) > 0.005 {
+		t.Errorf("circle covers %f, expected %f", c, math.Pi/4)
+	}
+}
+
+func TestPathReversedCutsHole(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	var inner Path
+	inner.AddRect(3, 3, 4, 4)
+
+	var p Path
+	p.AddRect(0, 0, 10, 10)
+	p.Append(inner.reversed())
+	p.fill(img, color.Black)
+
+	if _, _, _, a := img.At(5, 5).RGBA(); a != 0 {
+		t.Errorf("hole got alpha %x, expected 0", a)
+	}
+
+	if _, _, _, a := img.At(1, 1).RGBA(); a != 0xffff {
+		t.Errorf("ring got alpha %x, expected 0xffff", a)
+	}
+
+	if c := coverage(img); math.Abs(c-0.84) > 1e-6 {
+		t.Errorf("ring covers %f, expected 0.84", c)
+	}
+}
+
+func TestMaskLayerStampsModules(t *testing.T) {
+	bounds := image.Rect(0, 0, 40, 40)
+	src := image.NewUniform(color.NRGBA{0x20, 0x40, 0x80, 0xa0})
+
+	// Modules apart are drawn one by one, and overlapping modules through the
+	// layer's mask.
+	for _, step := range []float64{8, 6} {
+		got := image.NewRGBA(bounds)
+		draw.Draw(got, bounds, image.White, image.Point{}, draw.Src)
+		expected := image.NewRGBA(bounds)
+		draw.Draw(expected, bounds, image.White, image.Point{}, draw.Src)
+
+		l := newMaskLayer(bounds)
+		var p Path
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				x, y := 1.25+float64(i)*step, 1.5+float64(j)*step
+				l.addModule(CircleShape, x, y, 7.5, 0)
+				CircleShape.AddModule(&p, x, y, 7.5, 0)
+			}
+		}
+		l.draw(got, src)
+
+		if l.overlapped != (step < 7.5) {
+			t.Errorf("step %v got overlapped %t", step, l.overlapped)
+		}
+
+		// All the modules rasterized together, and drawn by the standard
+		// library.
+		coverage := p.rasterize()
+		draw.DrawMask(expected, coverage.Rect, src, coverage.Rect.Min, coverage, coverage.Rect.Min, draw.Over)
+
+		for i, v := range got.Pix {
+			if d := int(v) - int(expected.Pix[i]); d < -2 || d > 2 {
+				t.Fatalf("step %v pixel (%d,%d) got %d, expected %d", step,
+					i/4%40, i/4/40, v, expected.Pix[i])
+			}
+		}
+	}
+}
+
+func TestPathSVGData(t *testing.T) {
+	var p Path
+	p.AddRect(0, 0, 1, 2.5)
+	p.QuadTo(1, 1, 2, 0.3333333)
+
+	const expected = "M0 0L1 0L1 2.5L0 2.5ZQ1 1 2 0.333"
+	if got := p.svgData(); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestBeautifyImageModuleShapes(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	const moduleSize = 10
+	square := q.BeautifyImage(-moduleSize)
+
+	bitmap := q.Bitmap()
+	functionPatterns := q.symbol.functionPatternBitmap()
+
+	for _, shape := range []ModuleShape{CircleShape, DiamondShape, RoundedShape{0.3}, LiquidShape} {
+		q.ModuleShape = shape
+		img := q.BeautifyImage(-moduleSize)
+
+		for y := range bitmap {
+			for x := range bitmap[y] {
+				if !bitmap[y][x] {
+					continue
+				}
+
+				// Module centres are always dark.
+				cx, cy := x*moduleSize+moduleSize/2, y*moduleSize+moduleSize/2
+				if !isDark(img.At(cx, cy)) {
+					t.Fatalf("%T: module (%d,%d) centre is light", shape, x, y)
+				}
+
+				// Function pattern modules are unchanged squares.
+				if functionPatterns[y][x] {
+					for _, p := range []image.Point{{0, 0}, {moduleSize - 1, moduleSize - 1}} {
+						px, py := x*moduleSize+p.X, y*moduleSize+p.Y
+						if img.At(px, py) != square.At(px, py) {
+							t.Fatalf("%T: function module (%d,%d) changed", shape, x, y)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// An isolated data module's corner is light for a circle.
+	q.ModuleShape = CircleShape
+	img := q.BeautifyImage(-moduleSize)
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			if bitmap[y][x] && !functionPatterns[y][x] && neighbors(bitmap, x, y) == 0 {
+				if isDark(img.At(x*moduleSize, y*moduleSize)) {
+					t.Errorf("isolated circle module (%d,%d) has a dark corner", x, y)
+				}
+				return
+			}
+		}
+	}
+}
+
+// subpathAreas returns the signed area of each subpath of p, with curves
+// flattened. Clockwise subpaths (with the y axis pointing down) have a
+// positive area.
+func subpathAreas(p *Path) []float64 {
+	var areas []float64
+	var start, last [2]float64
+
+	edge := func(to [2]float64) {
+		areas[len(areas)-1] += (last[0]*to[1] - to[0]*last[1]) / 2
+		last = to
+	}
+
+	for _, o := range p.ops {
+		switch o.op {
+		case pathMoveTo:
+			areas = append(areas, 0)
+			start, last = o.pts[0], o.pts[0]
+		case pathLineTo:
+			edge(o.pts[0])
+		case pathQuadTo, pathCubeTo:
+			// Flatten the curve by evaluating it as a cubic Bézier curve.
+			c1, c2, end := o.pts[0], o.pts[0], o.pts[1]
+			if o.op == pathCubeTo {
+				c2, end = o.pts[1], o.pts[2]
+			}
+			p0 := last
+			for i := 1; i <= 16; i++ {
+				t := float64(i) / 16
+				u := 1 - t
+				var pt [2]float64
+				for j := range pt {
+					pt[j] = u*u*u*p0[j] + 3*u*u*t*c1[j] + 3*u*t*t*c2[j] + t*t*t*end[j]
+				}
+				edge(pt)
+			}
+		case pathClose:
+			edge(start)
+		}
+	}
+
+	return areas
+}
+
+func TestModuleShapesClockwise(t *testing.T) {
+	shapes := map[string]ModuleShape{
+		"square":  SquareShape,
+		"circle":  CircleShape,
+		"diamond": DiamondShape,
+		"rounded": RoundedShape{0.3},
+		"liquid":  LiquidShape,
+	}
+
+	for name, shape := range shapes {
+		for n := 0; n < 256; n++ {
+			var p Path
+			shape.AddModule(&p, 10, 20, 10, Neighbors(n))
+
+			for i, area := range subpathAreas(&p) {
+				if area <= 0 {
+					t.Errorf("%s with neighbours %08b: subpath %d has area %.2f, expected clockwise",
+						name, n, i, area)
+				}
+			}
+		}
+	}
+}
+
+func TestLiquidShapeJoins(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 30, 30))
+
+	// An L of three modules in a 10px grid. The inside corner is at (20,20).
+	bitmap := [][]bool{
+		{b0, b0, b0},
+		{b0, b1, b1},
+		{b0, b1, b0},
+	}
+
+	var p Path
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			if bitmap[y][x] {
+				LiquidShape.AddModule(&p, float64(x*10), float64(y*10), 10, neighbors(bitmap, x, y))
+			}
+		}
+	}
+	p.fill(img, color.Black)
+
+	// The outside corner is rounded.
+	if _, _, _, a := img.At(10, 10).RGBA(); a != 0 {
+		t.Errorf("outside corner got alpha %x, expected 0", a)
+	}
+
+	// The joined edges are solid.
+	if _, _, _, a := img.At(19, 10).RGBA(); a != 0xffff {
+		t.Errorf("joined edge got alpha %x, expected 0xffff", a)
+	}
+
+	// The inside corner is (mostly) filled.
+	if _, _, _, a := img.At(20, 20).RGBA(); a < 0xc000 {
+		t.Errorf("inside corner got alpha %x, expected mostly opaque", a)
+	}
+
+	// Away from the inside corner, the unset module is empty.
+	if _, _, _, a := img.At(25, 25).RGBA(); a != 0 {
+		t.Errorf("unset module got alpha %x, expected 0", a)
+	}
+}
@@ -0,0 +1,528 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Style is a serializable description of a QR Code's appearance, which
+// round-trips through JSON, e.g.:
+//
+//	{
+//	  "pixel_color": "#1a237e",
+//	  "module_shape": "rounded",
+//	  "finder_style": {"outer": "rounded", "inner": "circle"},
+//	  "logo": "logo.png",
+//	  "frame": {"shape": "bubble", "caption": "SCAN ME"}
+//	}
+//
+// Each field sets the QRCode field of the same name (see QRCode for details),
+// and is applied with ApplyStyle. Fields left empty keep the QRCode's current
+// value. Colours are written as hex strings (see HexColor), and enumerations
+// by name, e.g. "rounded" for EyeRounded.
+//
+// Images and fonts are given by file path. Relative paths are relative to the
+// style file read by LoadStyle, or else the working directory.
+//
+// A Style covers the QRCode's drawing fields, and the Frame. It intentionally
+// leaves out encoding options such as ParallelMasks, and the options of other
+// drawing methods, which are passed to them directly: The circular layout
+// (CircularImage and CircularSVG), dark mode (the DarkModeStyle of
+// DarkModeSVG and LightDarkImages), and halftones (Halftone's picture).
+type Style struct {
+	BackgroundColor *HexColor `json:"background_color,omitempty"`
+	BoxColor        *HexColor `json:"box_color,omitempty"`
+	PixelColor      *HexColor `json:"pixel_color,omitempty"`
+
+	// Name of the ModuleShape: "square", "circle", "diamond", "rounded",
+	// "liquid", or a shape added with RegisterModuleShape. "default" clears
+	// the ModuleShape, to draw plain square modules. ModuleRadius sets the
+	// Radius of "rounded", defaulting to 0.3.
+	ModuleShape  string  `json:"module_shape,omitempty"`
+	ModuleRadius float64 `json:"module_radius,omitempty"`
+
+	FinderStyle    *StyleEye `json:"finder_style,omitempty"`
+	AlignmentStyle *StyleEye `json:"alignment_style,omitempty"`
+
+	DataGradient   *StyleGradient `json:"data_gradient,omitempty"`
+	FinderGradient *StyleGradient `json:"finder_gradient,omitempty"`
+
+	// Path of the CenterLogo, drawn on a background LogoBackgroundOffset
+	// pixels wider than the logo (see SetCenterLogo).
+	Logo                 string     `json:"logo,omitempty"`
+	LogoBackgroundOffset int        `json:"logo_background_offset,omitempty"`
+	LogoStyle            *StyleLogo `json:"logo_style,omitempty"`
+
+	// Paths of the FinderPatternImage and AlignmentPatternImage.
+	FinderPatternImage    string `json:"finder_pattern_image,omitempty"`
+	AlignmentPatternImage string `json:"alignment_pattern_image,omitempty"`
+
+	// Path of the BackgroundImage. BackgroundMode is "dots" or "adjust".
+	BackgroundImage   string  `json:"background_image,omitempty"`
+	BackgroundMode    string  `json:"background_mode,omitempty"`
+	BackgroundDotSize float64 `json:"background_dot_size,omitempty"`
+
+	// Disable (true) or enable (false) the quiet zone.
+	DisableBorder *bool `json:"disable_border,omitempty"`
+
+	// Allow foreground colours and gradient stops too close to the
+	// background colour to be reliably scanned. Otherwise ApplyStyle fails
+	// if CheckContrast does. It sets no QRCode field.
+	AllowLowContrast bool `json:"allow_low_contrast,omitempty"`
+
+	// Scaling is "nearest", "integer" or "smooth".
+	Scaling     string `json:"scaling,omitempty"`
+	JPEGQuality int    `json:"jpeg_quality,omitempty"`
+
+	// Frame drawn around the QR Code. It isn't part of the QRCode, so isn't
+	// set by ApplyStyle: See LoadFrame.
+	Frame *StyleFrame `json:"frame,omitempty"`
+
+	// Directory relative paths are resolved against.
+	dir string
+}
+
+// StyleEye is the serializable form of an EyeStyle. Shapes are "square",
+// "rounded", "circle" or "leaf".
+type StyleEye struct {
+	Outer      string    `json:"outer,omitempty"`
+	Inner      string    `json:"inner,omitempty"`
+	OuterColor *HexColor `json:"outer_color,omitempty"`
+	InnerColor *HexColor `json:"inner_color,omitempty"`
+}
+
+// StyleGradient is the serializable form of a Gradient. Kind is "linear" or
+// "radial".
+type StyleGradient struct {
+	Kind  string              `json:"kind,omitempty"`
+	Angle float64             `json:"angle,omitempty"`
+	Stops []StyleGradientStop `json:"stops"`
+}
+
+// StyleGradientStop is the serializable form of a GradientStop.
+type StyleGradientStop struct {
+	Offset float64  `json:"offset"`
+	Color  HexColor `json:"color"`
+}
+
+// StyleLogo is the serializable form of a LogoStyle. Shape is "box" or
+// "circle". The logo is centred, unless Position is set to the [x, y] position
+// of its top left module.
+type StyleLogo struct {
+	Shape    string  `json:"shape,omitempty"`
+	Size     int     `json:"size,omitempty"`
+	Padding  int     `json:"padding,omitempty"`
+	Position *[2]int `json:"position,omitempty"`
+}
+
+// StyleFrame is the serializable form of a Frame. Shape is "border",
+// "rounded" or "bubble", and CaptionPosition is "below" or "above". Font is
+// the path of a TrueType or OpenType font file.
+type StyleFrame struct {
+	Shape           string    `json:"shape,omitempty"`
+	Color           *HexColor `json:"color,omitempty"`
+	Thickness       float64   `json:"thickness,omitempty"`
+	Caption         string    `json:"caption,omitempty"`
+	CaptionPosition string    `json:"caption_position,omitempty"`
+	CaptionColor    *HexColor `json:"caption_color,omitempty"`
+	Font            string    `json:"font,omitempty"`
+	FontSize        float64   `json:"font_size,omitempty"`
+}
+
+// Names of enumeration values in a Style, indexed by value.
+var (
+	eyeShapeNames       = []string{"square", "rounded", "circle", "leaf"}
+	gradientKindNames   = []string{"linear", "radial"}
+	logoShapeNames      = []string{"box", "circle"}
+	backgroundModeNames = []string{"dots", "adjust"}
+	scaleModeNames      = []string{"nearest", "integer", "smooth"}
+	frameShapeNames     = []string{"border", "rounded", "bubble"}
+	captionPosNames     = []string{"below", "above"}
+)
+
+// styleValue returns the value of the enumeration named name, or 0 if name is
+// empty. kind describes the enumeration in errors.
+func styleValue(kind string, names []string, name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	for i, n := range names {
+		if n == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown %s %q (expected one of %s)", kind, name, strings.Join(names, ", "))
+}
+
+// HexColor is a colour written as a hex string: "#rgb", "#rrggbb", or
+// "#rrggbbaa" with an alpha component (not premultiplied).
+type HexColor color.NRGBA
+
+// RGBA implements the color.Color interface.
+func (c HexColor) RGBA() (r, g, b, a uint32) {
+	return color.NRGBA(c).RGBA()
+}
+
+// MarshalText returns c as "#rrggbb", or "#rrggbbaa" if it is translucent.
+func (c HexColor) MarshalText() ([]byte, error) {
+	if c.A == 0xff {
+		return []byte(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)), nil
+	}
+
+	return []byte(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)), nil
+}
+
+// UnmarshalText parses a "#rgb", "#rrggbb" or "#rrggbbaa" hex colour.
+func (c *HexColor) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "#") {
+		return fmt.Errorf("invalid colour %q (expected #rrggbb)", s)
+	}
+
+	digits := s[1:]
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+
+	b, err := hex.DecodeString(digits)
+	if err != nil || len(b) != 4 {
+		return fmt.Errorf("invalid colour %q (expected #rrggbb)", s)
+	}
+
+	*c = HexColor{b[0], b[1], b[2], b[3]}
+	return nil
+}
+
+// moduleShapes are the ModuleShapes a Style can name.
+var moduleShapes = struct {
+	sync.RWMutex
+	m map[string]ModuleShape
+}{
+	m: map[string]ModuleShape{
+		"square":  SquareShape,
+		"circle":  CircleShape,
+		"diamond": DiamondShape,
+		"liquid":  LiquidShape,
+	},
+}
+
+// defaultModuleRadius is the Radius of the "rounded" module shape, if the
+// Style doesn't set one.
+const defaultModuleRadius = 0.3
+
+// RegisterModuleShape adds shape to the module shapes a Style can name, or
+// replaces an existing shape. "rounded" is reserved for RoundedShape, and
+// "default" for no shape.
+func RegisterModuleShape(name string, shape ModuleShape) {
+	moduleShapes.Lock()
+	defer moduleShapes.Unlock()
+
+	moduleShapes.m[name] = shape
+}
+
+// moduleShape returns the ModuleShape named name, with radius for "rounded".
+// "default" returns nil.
+func moduleShape(name string, radius float64) (ModuleShape, error) {
+	if name == "default" {
+		return nil, nil
+	}
+
+	if name == "rounded" {
+		if radius == 0 {
+			radius = defaultModuleRadius
+		}
+		return RoundedShape{radius}, nil
+	}
+
+	moduleShapes.RLock()
+	defer moduleShapes.RUnlock()
+
+	if shape, ok := moduleShapes.m[name]; ok {
+		return shape, nil
+	}
+
+	names := []string{"default", "rounded"}
+	for n := range moduleShapes.m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return nil, fmt.Errorf("unknown module shape %q (expected one of %s)", name, strings.Join(names, ", "))
+}
+
+// LoadStyle reads a JSON Style from the file at path. Relative paths in the
+// style are relative to the file's directory.
+func LoadStyle(path string) (*Style, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	s, err := ReadStyle(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	s.dir = filepath.Dir(path)
+
+	return s, nil
+}
+
+// ReadStyle reads a JSON Style from r. Unknown fields are an error, to catch
+// misspellings.
+func ReadStyle(r io.Reader) (*Style, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	var s Style
+	if err := d.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// path returns the path of a file named in s.
+func (s *Style) path(p string) string {
+	if s.dir == "" || filepath.IsAbs(p) {
+		return p
+	}
+
+	return filepath.Join(s.dir, p)
+}
+
+// ApplyStyle sets the QRCode's drawing options from s, loading any images it
+// names. If an error occurs, q is left unchanged.
+//
+// The resulting colours are checked with CheckContrast, unless
+// s.AllowLowContrast is set.
+func (q *QRCode) ApplyStyle(s *Style) error {
+	r := *q
+
+	if s.BackgroundColor != nil {
+		r.BackgroundColor = color.NRGBA(*s.BackgroundColor)
+	}
+	if s.BoxColor != nil {
+		r.BoxColor = color.NRGBA(*s.BoxColor)
+	}
+	if s.PixelColor != nil {
+		r.PixelColor = color.NRGBA(*s.PixelColor)
+	}
+
+	if s.ModuleShape != "" {
+		shape, err := moduleShape(s.ModuleShape, s.ModuleRadius)
+		if err != nil {
+			return err
+		}
+		r.ModuleShape = shape
+	}
+
+	var err error
+	if s.FinderStyle != nil {
+		if r.FinderStyle, err = s.FinderStyle.eyeStyle(); err != nil {
+			return err
+		}
+	}
+	if s.AlignmentStyle != nil {
+		if r.AlignmentStyle, err = s.AlignmentStyle.eyeStyle(); err != nil {
+			return err
+		}
+	}
+
+	if s.DataGradient != nil {
+		if r.DataGradient, err = s.DataGradient.gradient(); err != nil {
+			return err
+		}
+	}
+	if s.FinderGradient != nil {
+		if r.FinderGradient, err = s.FinderGradient.gradient(); err != nil {
+			return err
+		}
+	}
+
+	if s.Logo != "" {
+		if err := r.LoadAndSetCenterLogo(s.path(s.Logo), s.LogoBackgroundOffset); err != nil {
+			return err
+		}
+	}
+	if s.LogoStyle != nil {
+		if r.LogoStyle, err = s.LogoStyle.logoStyle(); err != nil {
+			return err
+		}
+	}
+
+	if s.FinderPatternImage != "" {
+		if err := r.LoadAndSetFinderPatternImage(s.path(s.FinderPatternImage)); err != nil {
+			return err
+		}
+	}
+	if s.AlignmentPatternImage != "" {
+		if err := r.LoadAndSetAlignmentPatternImage(s.path(s.AlignmentPatternImage)); err != nil {
+			return err
+		}
+	}
+
+	mode, err := styleValue("background mode", backgroundModeNames, s.BackgroundMode)
+	if err != nil {
+		return err
+	}
+	if s.BackgroundImage != "" {
+		if err := r.LoadAndSetBackgroundImage(s.path(s.BackgroundImage), BackgroundMode(mode)); err != nil {
+			return err
+		}
+	} else if s.BackgroundMode != "" {
+		r.BackgroundMode = BackgroundMode(mode)
+	}
+	if s.BackgroundDotSize != 0 {
+		r.BackgroundDotSize = s.BackgroundDotSize
+	}
+
+	if s.DisableBorder != nil {
+		r.DisableBorder = *s.DisableBorder
+	}
+
+	if s.Scaling != "" {
+		scaling, err := styleValue("scaling", scaleModeNames, s.Scaling)
+		if err != nil {
+			return err
+		}
+		r.Scaling = ScaleMode(scaling)
+	}
+	if s.JPEGQuality != 0 {
+		r.JPEGQuality = s.JPEGQuality
+	}
+
+	if !s.AllowLowContrast {
+		if err := r.CheckContrast(); err != nil {
+			return err
+		}
+	}
+
+	// The cached images may be drawn with the old colours.
+	r.centerLogoCache = nil
+	r.styledLogoCache = nil
+	r.finderPatternImageCache = nil
+	r.alignmentPatternImageCache = nil
+	r.backgroundImageCache = nil
+
+	*q = r
+	return nil
+}
+
+// eyeStyle returns e as an EyeStyle.
+func (e *StyleEye) eyeStyle() (*EyeStyle, error) {
+	outer, err := styleValue("eye shape", eyeShapeNames, e.Outer)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := styleValue("eye shape", eyeShapeNames, e.Inner)
+	if err != nil {
+		return nil, err
+	}
+
+	style := &EyeStyle{Outer: EyeShape(outer), Inner: EyeShape(inner)}
+	if e.OuterColor != nil {
+		style.OuterColor = color.NRGBA(*e.OuterColor)
+	}
+	if e.InnerColor != nil {
+		style.InnerColor = color.NRGBA(*e.InnerColor)
+	}
+
+	return style, nil
+}
+
+// gradient returns g as a Gradient.
+func (g *StyleGradient) gradient() (*Gradient, error) {
+	kind, err := styleValue("gradient kind", gradientKindNames, g.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	gradient := &Gradient{Kind: GradientKind(kind), Angle: g.Angle}
+	for _, s := range g.Stops {
+		gradient.Stops = append(gradient.Stops, GradientStop{Offset: s.Offset, Color: color.NRGBA(s.Color)})
+	}
+
+	return gradient, nil
+}
+
+// logoStyle returns l as a LogoStyle.
+func (l *StyleLogo) logoStyle() (*LogoStyle, error) {
+	shape, err := styleValue("logo shape", logoShapeNames, l.Shape)
+	if err != nil {
+		return nil, err
+	}
+
+	style := &LogoStyle{Shape: LogoShape(shape), Size: l.Size, Padding: l.Padding}
+	if l.Position != nil {
+		style.Placement = LogoAt
+		style.Position = image.Pt(l.Position[0], l.Position[1])
+	}
+
+	return style, nil
+}
+
+// LoadFrame returns the style's Frame, loading its font, or nil if the style
+// has no frame.
+func (s *Style) LoadFrame() (*Frame, error) {
+	f := s.Frame
+	if f == nil {
+		return nil, nil
+	}
+
+	shape, err := styleValue("frame shape", frameShapeNames, f.Shape)
+	if err != nil {
+		return nil, err
+	}
+	position, err := styleValue("caption position", captionPosNames, f.CaptionPosition)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := &Frame{
+		Shape:           FrameShape(shape),
+		Thickness:       f.Thickness,
+		Caption:         f.Caption,
+		CaptionPosition: CaptionPosition(position),
+		FontSize:        f.FontSize,
+	}
+	if f.Color != nil {
+		frame.Color = color.NRGBA(*f.Color)
+	}
+	if f.CaptionColor != nil {
+		frame.CaptionColor = color.NRGBA(*f.CaptionColor)
+	}
+
+	if f.Font != "" {
+		data, err := ioutil.ReadFile(s.path(f.Font))
+		if err != nil {
+			return nil, err
+		}
+
+		if frame.Font, err = sfnt.Parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Font, err)
+		}
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,240 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHexColor(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected HexColor
+		marshal  string
+	}{
+		{"#1a237e", HexColor{0x1a, 0x23, 0x7e, 0xff}, "#1a237e"},
+		{"#FFF", HexColor{0xff, 0xff, 0xff, 0xff}, "#ffffff"},
+		{"#00000080", HexColor{0, 0, 0, 0x80}, "#00000080"},
+	}
+
+	for _, test := range tests {
+		var c HexColor
+		if err := c.UnmarshalText([]byte(test.text)); err != nil {
+			t.Errorf("%q got error %s", test.text, err.Error())
+			continue
+		}
+		if c != test.expected {
+			t.Errorf("%q got %v, expected %v", test.text, c, test.expected)
+		}
+
+		text, _ := c.MarshalText()
+		if string(text) != test.marshal {
+			t.Errorf("%q marshalled as %q, expected %q", test.text, text, test.marshal)
+		}
+	}
+
+	for _, text := range []string{"", "1a237e", "#12", "#1a237g"} {
+		var c HexColor
+		if err := c.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("%q got no error", text)
+		}
+	}
+}
+
+func TestStyleRoundTrip(t *testing.T) {
+	pixel := HexColor{0x1a, 0x23, 0x7e, 0xff}
+	translucent := HexColor{0xc6, 0x28, 0x28, 0x80}
+	disableBorder := true
+
+	s := &Style{
+		BackgroundColor: &HexColor{0xff, 0xff, 0xf0, 0xff},
+		PixelColor:      &pixel,
+		ModuleShape:     "rounded",
+		ModuleRadius:    0.4,
+		FinderStyle:     &StyleEye{Outer: "rounded", Inner: "circle", InnerColor: &translucent},
+		DataGradient: &StyleGradient{Kind: "radial", Stops: []StyleGradientStop{
+			{0, pixel}, {1, translucent},
+		}},
+		Logo:             "logo.png",
+		LogoStyle:        &StyleLogo{Shape: "circle", Padding: 1, Position: &[2]int{3, 4}},
+		DisableBorder:    &disableBorder,
+		AllowLowContrast: true,
+		Scaling:          "smooth",
+		Frame:            &StyleFrame{Shape: "bubble", Caption: "SCAN ME", CaptionPosition: "above"},
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	decoded, err := ReadStyle(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reflect.DeepEqual(s, decoded) {
+		t.Errorf("got %+v, expected %+v", decoded, s)
+	}
+
+	if _, err := ReadStyle(strings.NewReader(`{"pixel_colour": "#000000"}`)); err == nil {
+		t.Error("misspelt field got no error")
+	}
+}
+
+func TestApplyStyle(t *testing.T) {
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, rectangleImage(40, 40, red)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "logo.png"), encoded.Bytes(), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	style := `{
+		"pixel_color": "#1a237e",
+		"module_shape": "circle",
+		"finder_style": {"outer": "leaf", "outer_color": "#c62828"},
+		"finder_gradient": {"angle": 45, "stops": [{"offset": 0, "color": "#000"}, {"offset": 1, "color": "#333"}]},
+		"logo": "logo.png",
+		"logo_style": {"shape": "box", "size": 5},
+		"background_mode": "adjust",
+		"scaling": "integer",
+		"disable_border": true,
+		"frame": {"caption": "SCAN ME", "color": "#1a237e"}
+	}`
+
+	path := filepath.Join(dir, "style.json")
+	if err := ioutil.WriteFile(path, []byte(style), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s, err := LoadStyle(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q, err := New("https://example.org", Highest)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := q.ApplyStyle(s); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if q.PixelColor != (color.NRGBA{0x1a, 0x23, 0x7e, 0xff}) {
+		t.Errorf("got PixelColor %v", q.PixelColor)
+	}
+	if q.BoxColor != color.Black {
+		t.Errorf("got BoxColor %v, expected it unchanged", q.BoxColor)
+	}
+	if q.ModuleShape == nil {
+		t.Error("got no ModuleShape")
+	}
+	if q.FinderStyle == nil || q.FinderStyle.Outer != EyeLeaf || q.FinderStyle.Inner != EyeSquare ||
+		q.FinderStyle.OuterColor != (color.NRGBA{0xc6, 0x28, 0x28, 0xff}) {
+		t.Errorf("got FinderStyle %+v", q.FinderStyle)
+	}
+	if g := q.FinderGradient; g == nil || g.Kind != LinearGradient || g.Angle != 45 || len(g.Stops) != 2 {
+		t.Errorf("got FinderGradient %+v", g)
+	}
+	if q.CenterLogo == nil || (*q.CenterLogo).Bounds() != image.Rect(0, 0, 40, 40) {
+		t.Error("logo not loaded relative to the style file")
+	}
+	if q.LogoStyle == nil || q.LogoStyle.Size != 5 || q.LogoStyle.Placement != LogoCentre {
+		t.Errorf("got LogoStyle %+v", q.LogoStyle)
+	}
+	if q.BackgroundMode != BackgroundAdjust || q.Scaling != ScaleInteger || !q.DisableBorder {
+		t.Errorf("got BackgroundMode %d, Scaling %d, DisableBorder %t", q.BackgroundMode, q.Scaling, q.DisableBorder)
+	}
+
+	frame, err := s.LoadFrame()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if frame == nil || frame.Caption != "SCAN ME" || frame.Shape != FrameBorder || frame.Color == nil {
+		t.Errorf("got Frame %+v", frame)
+	}
+
+	// Errors leave the QRCode unchanged.
+	for _, bad := range []*Style{
+		{PixelColor: &HexColor{0xff, 0, 0, 0xff}, ModuleShape: "star"},
+		{PixelColor: &HexColor{0xff, 0, 0, 0xff}, FinderStyle: &StyleEye{Outer: "hexagon"}},
+		{PixelColor: &HexColor{0xff, 0, 0, 0xff}, Logo: "missing.png"},
+	} {
+		if err := q.ApplyStyle(bad); err == nil {
+			t.Errorf("style %+v got no error", bad)
+		}
+	}
+	if q.PixelColor != (color.NRGBA{0x1a, 0x23, 0x7e, 0xff}) {
+		t.Errorf("failed style changed PixelColor to %v", q.PixelColor)
+	}
+
+	// Colours too close to the background are an error, unless allowed.
+	lowContrast := &Style{DataGradient: &StyleGradient{Stops: []StyleGradientStop{
+		{0, HexColor{0, 0, 0, 0xff}}, {1, HexColor{0xf0, 0xf0, 0xf0, 0xff}},
+	}}}
+	if err := q.ApplyStyle(lowContrast); err == nil || !strings.Contains(err.Error(), "DataGradient stop 1") {
+		t.Errorf("low contrast gradient stop got error %v", err)
+	}
+	if q.DataGradient != nil {
+		t.Error("low contrast style set DataGradient")
+	}
+	lowContrast.AllowLowContrast = true
+	if err := q.ApplyStyle(lowContrast); err != nil || q.DataGradient == nil {
+		t.Errorf("allowed low contrast gradient got error %v", err)
+	}
+	q.DataGradient = nil
+
+	// The border can be enabled again, but is left alone if not given.
+	if err := q.ApplyStyle(&Style{}); err != nil || !q.DisableBorder {
+		t.Errorf("empty style changed DisableBorder (error %v)", err)
+	}
+	if err := q.ApplyStyle(&Style{DisableBorder: new(bool)}); err != nil || q.DisableBorder {
+		t.Errorf("disable_border false got DisableBorder %t (error %v)", q.DisableBorder, err)
+	}
+
+	// The module shape can be cleared.
+	if err := q.ApplyStyle(&Style{ModuleShape: "default"}); err != nil || q.ModuleShape != nil {
+		t.Errorf("default module shape got %v (error %v), expected none", q.ModuleShape, err)
+	}
+}
+
+func TestRegisterModuleShape(t *testing.T) {
+	var called bool
+	RegisterModuleShape("test-dot", ModuleShapeFunc(func(p *Path, x, y, size float64, n Neighbors) {
+		called = true
+		p.AddCircle(x+size/2, y+size/2, size/4)
+	}))
+
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := q.ApplyStyle(&Style{ModuleShape: "test-dot"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := q.SVG(256); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !called {
+		t.Error("registered shape not used")
+	}
+}
@@ -0,0 +1,238 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// SVG returns the QR Code as an SVG image.
+//
+// size is both the image width and height in pixels. Negative values for size
+// set the size of each module instead: See the documentation for Image(). The
+// image is drawn in vector form, and is sharp at any scale.
+//
+// The data modules are drawn with ModuleShape.
+func (q *QRCode) SVG(size int) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := q.WriteSVG(&b, size); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteSVG writes the QR Code as an SVG image to w. See SVG for details.
+func (q *QRCode) WriteSVG(w io.Writer, size int) error {
+	// Build QR code.
+	q.encode()
+
+	realSize := q.symbol.size
+
+	// Variable size support.
+	if size < 0 {
+		size = size * -1 * realSize
+	}
+
+	if size < realSize {
+		size = realSize
+	}
+
+	return q.writeSVG(w, strconv.Itoa(size))
+}
+
+// writeSVG writes the encoded QR Code as an SVG image to w. The width (and
+// height) attribute is given by width, which may include a unit, e.g. "30mm".
+func (q *QRCode) writeSVG(w io.Writer, width string) error {
+	realSize := q.symbol.size
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%s" height="%s" viewBox="0 0 %d %d">
+`, width, width, realSize, realSize)
+
+	fmt.Fprintf(&b, "<rect width=\"%d\" height=\"%d\" %s/>\n", realSize, realSize, svgFill(q.BackgroundColor))
+
+	q.writeSVGSymbol(&b)
+
+	b.WriteString("</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeSVGSymbol writes the symbol's modules as SVG elements, in a coordinate
+// system one unit per module with the origin at the top left of the quiet
+// zone. The background is not drawn.
+func (q *QRCode) writeSVGSymbol(b *bytes.Buffer) {
+	q.writeSVGGradients(b)
+
+	for _, l := range q.symbolLayers() {
+		writeSVGPath(b, l.path, l.fill, l.crisp)
+	}
+}
+
+// writeSVGGradients writes the definitions of the gradients the symbol's
+// module fills refer to, if any.
+func (q *QRCode) writeSVGGradients(b *bytes.Buffer) {
+	if q.DataGradient != nil || q.FinderGradient != nil {
+		b.WriteString("<defs>\n")
+		if q.DataGradient != nil {
+			q.writeSVGGradient(b, "qrcode-data-gradient", q.DataGradient)
+		}
+		if q.FinderGradient != nil {
+			q.writeSVGGradient(b, "qrcode-finder-gradient", q.FinderGradient)
+		}
+		b.WriteString("</defs>\n")
+	}
+}
+
+// symbolLayer is part of the symbol drawn by the vector renderers, as a single
+// path with one fill.
+type symbolLayer struct {
+	path *Path
+
+	// SVG fill attributes (see svgFill), which may refer to the gradients
+	// written by writeSVGGradients.
+	fill string
+
+	// Solid colour, used by renderers without gradient support. Gradients
+	// are approximated by their midpoint colour.
+	color color.Color
+
+	// True if the path is only module aligned rectangles.
+	crisp bool
+
+	// True for the finder patterns, which are drawn with BoxColor by default.
+	finder bool
+}
+
+// symbolLayers returns the symbol's modules as paths, in a coordinate system
+// one unit per module with the origin at the top left of the quiet zone. The
+// background is not included.
+func (q *QRCode) symbolLayers() []symbolLayer {
+	realSize := q.symbol.size
+
+	bitmap := q.symbol.bitmap()
+	boxes := q.symbol.finderPatternBitmap()
+	alignments := q.symbol.alignmentPatternBitmap()
+	functionPatterns := q.symbol.functionPatternBitmap()
+
+	// Module fills.
+	pixelFill, boxFill := svgFill(q.PixelColor), svgFill(q.BoxColor)
+	pixelColor, boxColor := q.PixelColor, q.BoxColor
+	if q.DataGradient != nil {
+		pixelFill = `fill="url(#qrcode-data-gradient)"`
+		pixelColor = q.DataGradient.colorAt(0.5)
+	}
+	if q.FinderGradient != nil {
+		boxFill = `fill="url(#qrcode-finder-gradient)"`
+		boxColor = q.FinderGradient.colorAt(0.5)
+	}
+
+	var boxPath, modulePath Path
+
+	for y := 0; y < realSize; y++ {
+		for x := 0; x < realSize; x++ {
+			switch {
+			case boxes[y][x] && q.FinderStyle != nil,
+				alignments[y][x] && q.AlignmentStyle != nil:
+				// Added below.
+			case boxes[y][x]:
+				// Finder patterns, in horizontal runs.
+				run := 1
+				for x+run < realSize && boxes[y][x+run] {
+					run++
+				}
+
+				boxPath.AddRect(float64(x), float64(y), float64(run), 1)
+				x += run - 1
+			case bitmap[y][x] && q.ModuleShape != nil && !functionPatterns[y][x]:
+				q.ModuleShape.AddModule(&modulePath, float64(x), float64(y), 1,
+					neighbors(bitmap, x, y))
+			case bitmap[y][x]:
+				// Square modules, in horizontal runs.
+				run := 1
+				for x+run < realSize && bitmap[y][x+run] && !boxes[y][x+run] &&
+					(q.AlignmentStyle == nil || !alignments[y][x+run]) &&
+					(q.ModuleShape == nil || functionPatterns[y][x+run]) {
+					run++
+				}
+
+				modulePath.AddRect(float64(x), float64(y), float64(run), 1)
+				x += run - 1
+			}
+		}
+	}
+
+	layers := []symbolLayer{{&boxPath, boxFill, boxColor, q.ModuleShape == nil, true}}
+
+	unit := func(x, y int) (float64, float64) {
+		return float64(x), float64(y)
+	}
+
+	if q.FinderStyle != nil {
+		var outer, inner Path
+		q.FinderStyle.addFinderEyes(q.symbol, &outer, &inner, unit, 1)
+
+		outerFill, innerFill := q.FinderStyle.svgFills(boxFill)
+		outerColor, innerColor := q.FinderStyle.colors(boxColor)
+		layers = append(layers,
+			symbolLayer{&outer, outerFill, outerColor, false, true},
+			symbolLayer{&inner, innerFill, innerColor, false, true})
+	}
+
+	if q.AlignmentStyle != nil {
+		var outer, inner Path
+		q.AlignmentStyle.addAlignmentEyes(q.symbol, &outer, &inner, unit, 1)
+
+		outerFill, innerFill := q.AlignmentStyle.svgFills(pixelFill)
+		outerColor, innerColor := q.AlignmentStyle.colors(pixelColor)
+		layers = append(layers,
+			symbolLayer{&outer, outerFill, outerColor, false, false},
+			symbolLayer{&inner, innerFill, innerColor, false, false})
+	}
+
+	return append(layers, symbolLayer{&modulePath, pixelFill, pixelColor, q.ModuleShape == nil, false})
+}
+
+// writeSVGPath writes p as an SVG path element with the fill attributes fill
+// (see svgFill). Paths made only of module aligned rectangles are rendered
+// with crisp edges, to avoid hairline gaps between the rectangles.
+func writeSVGPath(b *bytes.Buffer, p *Path, fill string, crisp bool) {
+	if p.Empty() {
+		return
+	}
+
+	var rendering string
+	if crisp {
+		rendering = ` shape-rendering="crispEdges"`
+	}
+
+	fmt.Fprintf(b, "<path d=\"%s\" %s%s/>\n", p.svgData(), fill, rendering)
+}
+
+// svgColor returns c as an SVG colour and opacity.
+func svgColor(c color.Color) (string, float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B), float64(n.A) / 0xff
+}
+
+// svgFill returns the SVG attributes to fill with c.
+func svgFill(c color.Color) string {
+	hex, opacity := svgColor(c)
+
+	if opacity == 1 {
+		return fmt.Sprintf(`fill="%s"`, hex)
+	}
+
+	return fmt.Sprintf(`fill="%s" fill-opacity="%s"`, hex, formatCoordinate(opacity))
+}
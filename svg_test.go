@@ -0,0 +1,116 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// svgDocument is the subset of an SVG image checked by the tests.
+type svgDocument struct {
+	Width   string `xml:"width,attr"`
+	Height  string `xml:"height,attr"`
+	ViewBox string `xml:"viewBox,attr"`
+	Rects   []struct {
+		Fill string `xml:"fill,attr"`
+	} `xml:"rect"`
+	Paths []struct {
+		D           string `xml:"d,attr"`
+		Fill        string `xml:"fill,attr"`
+		FillOpacity string `xml:"fill-opacity,attr"`
+	} `xml:"path"`
+}
+
+func parseSVG(t *testing.T, b []byte) svgDocument {
+	var doc svgDocument
+
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("invalid SVG: %s\n%s", err, b)
+	}
+
+	return doc
+}
+
+func TestSVG(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	q.BoxColor = color.RGBA{0xff, 0, 0, 0xff}
+	q.PixelColor = color.NRGBA{0, 0, 0x80, 0x80}
+
+	b, err := q.SVG(-4)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	doc := parseSVG(t, b)
+
+	if doc.Width != "132" || doc.Height != "132" || doc.ViewBox != "0 0 33 33" {
+		t.Errorf("got size %s x %s viewBox %q, expected 132 x 132 viewBox \"0 0 33 33\"",
+			doc.Width, doc.Height, doc.ViewBox)
+	}
+
+	if len(doc.Rects) != 1 || doc.Rects[0].Fill != "#ffffff" {
+		t.Errorf("expected a single white background rect")
+	}
+
+	if len(doc.Paths) != 2 {
+		t.Fatalf("got %d paths, expected 2", len(doc.Paths))
+	}
+
+	if doc.Paths[0].Fill != "#ff0000" {
+		t.Errorf("finder pattern fill got %s, expected #ff0000", doc.Paths[0].Fill)
+	}
+
+	if doc.Paths[1].Fill != "#000080" || doc.Paths[1].FillOpacity != "0.502" {
+		t.Errorf("module fill got %s opacity %s, expected #000080 opacity 0.502",
+			doc.Paths[1].Fill, doc.Paths[1].FillOpacity)
+	}
+
+	// Square modules are drawn with straight lines only.
+	if strings.Contains(doc.Paths[1].D, "C") {
+		t.Error("square modules contain curves")
+	}
+
+	q.ModuleShape = CircleShape
+	b, err = q.SVG(256)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	doc = parseSVG(t, b)
+	if doc.Width != "256" || !strings.Contains(doc.Paths[1].D, "C") {
+		t.Error("circle modules not drawn with curves")
+	}
+}
+
+func TestSVGFormat(t *testing.T) {
+	q, err := New("https://example.org", Medium)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var b bytes.Buffer
+	if err := q.Encode(&b, FormatSVG, 100); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if doc := parseSVG(t, b.Bytes()); doc.Width != "100" {
+		t.Errorf("got width %s, expected 100", doc.Width)
+	}
+
+	b.Reset()
+	if err := q.EncodePrint(&b, FormatSVG, 30, Millimetre, 300); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if doc := parseSVG(t, b.Bytes()); doc.Width != "30mm" || doc.Height != "30mm" {
+		t.Errorf("got size %s x %s, expected 30mm x 30mm", doc.Width, doc.Height)
+	}
+}
@@ -5,6 +5,7 @@ package qrcode
 
 import (
 	"image"
+	"math/bits"
 )
 
 // symbol is a 2D array of bits representing a QR Code symbol.
@@ -26,15 +27,20 @@ import (
 // border) is returned by bitmap().
 //
 type symbol struct {
-	finderPatternModule    [][]bool
-	alignmentPatternModule [][]bool
+	finderPatternModule    bitMatrix
+	alignmentPatternModule bitMatrix
 
-	// Value of module at [y][x]. True is set.
-	module [][]bool
+	// Set if the module at (x, y) is part of a function pattern (finder,
+	// alignment or timing pattern, or format or version information), rather
+	// than data.
+	functionModule bitMatrix
 
-	// True if the module at [y][x] is used (to either true or false).
+	// Value of module at (x, y). Set is dark.
+	module bitMatrix
+
+	// Set if the module at (x, y) is used (to either true or false).
 	// Used to identify unused modules.
-	isUsed [][]bool
+	isUsed bitMatrix
 
 	// Combined width/height of the symbol and quiet zones.
 	//
@@ -45,9 +51,10 @@ type symbol struct {
 	finderPatternTLPoint, finderPatternTRPoint, finderPatternBLPoint image.Point
 	finderPatternSize                                                int
 
-	// Width/height of the last bottom right alignment pattern only.
-	alignmentPatternPoint image.Point
-	alignmentPatternSize  int
+	// Top left of each alignment pattern, and the width/height of a single
+	// alignment pattern only.
+	alignmentPatternPoints []image.Point
+	alignmentPatternSize   int
 
 	// Width/height of the symbol only.
 	symbolSize int
@@ -58,38 +65,46 @@ type symbol struct {
 
 // newSymbol constructs a symbol of size size*size, with a border of
 // quietZoneSize.
+//
+// The modules are stored as bitMatrix values covering the quiet zone too, so
+// the matrix coordinates are those of bitmap().
 func newSymbol(size int, quietZoneSize int) *symbol {
 	var m symbol
 
-	m.finderPatternModule = make([][]bool, size+2*quietZoneSize)
-	m.alignmentPatternModule = make([][]bool, size+2*quietZoneSize)
-	m.module = make([][]bool, size+2*quietZoneSize)
-	m.isUsed = make([][]bool, size+2*quietZoneSize)
-
-	for i := range m.module {
-		m.finderPatternModule[i] = make([]bool, size+2*quietZoneSize)
-		m.alignmentPatternModule[i] = make([]bool, size+2*quietZoneSize)
-		m.module[i] = make([]bool, size+2*quietZoneSize)
-		m.isUsed[i] = make([]bool, size+2*quietZoneSize)
-	}
-
 	m.size = size + 2*quietZoneSize
 	m.symbolSize = size
 	m.quietZoneSize = quietZoneSize
 
+	m.finderPatternModule = newBitMatrix(m.size)
+	m.alignmentPatternModule = newBitMatrix(m.size)
+	m.functionModule = newBitMatrix(m.size)
+	m.module = newBitMatrix(m.size)
+	m.isUsed = newBitMatrix(m.size)
+
 	return &m
 }
 
+// clone returns a copy of m, to be changed independently. The finder,
+// alignment and function pattern modules are shared, as they're never changed
+// after the function patterns are added.
+func (m *symbol) clone() *symbol {
+	c := *m
+	c.module = m.module.clone()
+	c.isUsed = m.isUsed.clone()
+
+	return &c
+}
+
 // get returns the module value at (x, y).
 func (m *symbol) get(x int, y int) (v bool) {
-	v = m.module[y+m.quietZoneSize][x+m.quietZoneSize]
+	v = m.module.get(x+m.quietZoneSize, y+m.quietZoneSize)
 	return
 }
 
 // empty returns true if the module at (x, y) has not been set (to either true
 // or false).
 func (m *symbol) empty(x int, y int) bool {
-	return !m.isUsed[y+m.quietZoneSize][x+m.quietZoneSize]
+	return !m.isUsed.get(x+m.quietZoneSize, y+m.quietZoneSize)
 }
 
 // numEmptyModules returns the number of empty modules.
@@ -97,82 +112,116 @@ func (m *symbol) empty(x int, y int) bool {
 // Initially numEmptyModules is symbolSize * symbolSize. After every module has
 // been set (to either true or false), the number of empty modules is zero.
 func (m *symbol) numEmptyModules() int {
-	var count int
-	for y := 0; y < m.symbolSize; y++ {
-		for x := 0; x < m.symbolSize; x++ {
-			if !m.isUsed[y+m.quietZoneSize][x+m.quietZoneSize] {
-				count++
-			}
-		}
-	}
-
-	return count
+	// Modules in the quiet zone are never used.
+	return m.symbolSize*m.symbolSize - m.isUsed.count()
 }
 
 // set sets the module at (x, y) to v.
 func (m *symbol) set(x int, y int, v bool) {
-	m.module[y+m.quietZoneSize][x+m.quietZoneSize] = v
-	m.isUsed[y+m.quietZoneSize][x+m.quietZoneSize] = true
+	m.module.set(x+m.quietZoneSize, y+m.quietZoneSize, v)
+	m.isUsed.set(x+m.quietZoneSize, y+m.quietZoneSize, true)
 }
 
-// set2dPattern sets a 2D array of modules, starting at (x, y).
+// set2dPattern sets a 2D array of modules, starting at (x, y). Each row of the
+// pattern is set a word at a time.
 func (m *symbol) set2dPattern(x int, y int, v [][]bool) {
 	for j, row := range v {
-		for i, value := range row {
-			m.set(x+i, y+j, value)
-		}
+		m.module.setBits(x+m.quietZoneSize, y+j+m.quietZoneSize, len(row), packBools(row))
+		m.isUsed.setBits(x+m.quietZoneSize, y+j+m.quietZoneSize, len(row), ^uint64(0))
 	}
 }
 
 // bitmap returns the entire symbol, including the quiet zone.
 func (m *symbol) bitmap() [][]bool {
-	module := make([][]bool, len(m.module))
-
-	for i := range m.module {
-		module[i] = m.module[i][:]
-	}
-
-	return module
+	return m.module.bools()
 }
 
-// set2dPattern sets a 2D array of modules, starting at (x, y).
+// set2dPatternForFinder sets a 2D array of modules, starting at (x, y).
 func (m *symbol) set2dPatternForFinder(x int, y int, v [][]bool) {
 	for j, row := range v {
-		for i, value := range row {
-			m.finderPatternModule[y+j+m.quietZoneSize][x+i+m.quietZoneSize] = value
-		}
+		m.finderPatternModule.setBits(x+m.quietZoneSize, y+j+m.quietZoneSize, len(row), packBools(row))
 	}
 }
 
 // finderPatternBitmap returns only toggles for the finder patterns, sized the same as bitmap().
 func (m *symbol) finderPatternBitmap() [][]bool {
-	module := make([][]bool, len(m.finderPatternModule))
+	return m.finderPatternModule.bools()
+}
 
-	for i := range m.finderPatternModule {
-		module[i] = m.finderPatternModule[i][:]
+// set2dPatternForAlignment sets a 2D array of modules, starting at (x, y).
+func (m *symbol) set2dPatternForAlignment(x int, y int, v [][]bool) {
+	for j, row := range v {
+		m.alignmentPatternModule.setBits(x+m.quietZoneSize, y+j+m.quietZoneSize, len(row), packBools(row))
 	}
+}
 
-	return module
+// alignmentPatternBitmap returns only toggles for the alignment patterns, sized the same as bitmap().
+func (m *symbol) alignmentPatternBitmap() [][]bool {
+	return m.alignmentPatternModule.bools()
 }
 
-// set2dPatternForLastAlignment sets a 2D array of modules, starting at (x, y).
-func (m *symbol) set2dPatternForLastAlignment(x int, y int, v [][]bool) {
-	for j, row := range v {
-		for i, value := range row {
-			m.alignmentPatternModule[y+j+m.quietZoneSize][x+i+m.quietZoneSize] = value
+// markFunctionPatterns records every module set so far as a function pattern
+// module. It is called after the function patterns are added, and before the
+// data.
+func (m *symbol) markFunctionPatterns() {
+	copy(m.functionModule.words, m.isUsed.words)
+}
+
+// functionPatternBitmap returns toggles for the function pattern modules, sized
+// the same as bitmap().
+func (m *symbol) functionPatternBitmap() [][]bool {
+	return m.functionModule.bools()
+}
+
+// maskPattern returns the modules inverted by mask: The data modules, i.e.
+// those which aren't function pattern modules, for which dataMask is true.
+// markFunctionPatterns must already have been called.
+func (m *symbol) maskPattern(mask int) bitMatrix {
+	p := newBitMatrix(m.size)
+
+	// Every mask repeats itself every 12 rows.
+	const period = 12
+
+	for y := 0; y < m.symbolSize; y++ {
+		row := p.row(y + m.quietZoneSize)
+
+		if y >= period {
+			copy(row, p.row(y-period+m.quietZoneSize))
+			continue
+		}
+
+		for x := 0; x < m.symbolSize; x++ {
+			if dataMask(mask, x, y) {
+				row[(x+m.quietZoneSize)/64] |= 1 << uint((x+m.quietZoneSize)%64)
+			}
 		}
 	}
-}
 
-// lastAlignmentPatternBitmap returns only toggles for the finder patterns, sized the same as bitmap().
-func (m *symbol) lastAlignmentPatternBitmap() [][]bool {
-	module := make([][]bool, len(m.alignmentPatternModule))
+	for i, w := range m.functionModule.words {
+		p.words[i] &^= w
+	}
+
+	return p
+}
 
-	for i := range m.alignmentPatternModule {
-		module[i] = m.alignmentPatternModule[i][:]
+// symbolColumns returns a row mask with the bits set for the columns from x
+// to the right edge of the symbol. x is a bitmap() coordinate.
+func (m *symbol) symbolColumns(x int) []uint64 {
+	columns := make([]uint64, m.module.stride)
+	for ; x < m.quietZoneSize+m.symbolSize; x++ {
+		columns[x/64] |= 1 << uint(x%64)
 	}
 
-	return module
+	return columns
+}
+
+// inSymbol returns true if the module at (x, y) of bitmap() is part of the
+// symbol, rather than the quiet zone.
+func (m *symbol) inSymbol(x int, y int) bool {
+	x -= m.quietZoneSize
+	y -= m.quietZoneSize
+
+	return x >= 0 && y >= 0 && x < m.symbolSize && y < m.symbolSize
 }
 
 func (m *symbol) finderPatternPoints() (image.Point, image.Point, image.Point) {
@@ -190,9 +239,9 @@ func (m *symbol) borderSize() int {
 func (m *symbol) string() string {
 	var result string
 
-	for _, row := range m.module {
-		for _, value := range row {
-			switch value {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			switch m.module.get(x, y) {
 			case true:
 				result += "  "
 			case false:
@@ -217,8 +266,13 @@ const (
 
 // penaltyScore returns the penalty score of the symbol. The penalty score
 // consists of the sum of the four individual penalty types.
+//
+// The penalties are calculated together by penalties(). penalty1 to penalty4
+// calculate each one separately, and are the reference it's tested against.
 func (m *symbol) penaltyScore() int {
-	return m.penalty1() + m.penalty2() + m.penalty3() + m.penalty4()
+	p1, p2, p3, p4 := m.penalties()
+
+	return p1 + p2 + p3 + p4
 }
 
 // penalty1 returns the penalty score for "adjacent modules in row/column with
@@ -227,117 +281,149 @@ func (m *symbol) penaltyScore() int {
 // The numbers of adjacent matching modules and scores are:
 // 0-5: score = 0
 // 6+ : score = penaltyWeight1 + (numAdjacentModules - 5)
+//
+// The runs are found a word at a time. A run of n modules is n-1 consecutive
+// modules the same colour as the previous one, and scores n-2 = (n-6) + 4. Of
+// those, (n-6)+1 end a run of 5 matches and (n-6) end a run of 6, so the score
+// is 4 * the number of runs of 5, less 3 * the number of runs of 6.
 func (m *symbol) penalty1() int {
-	penalty := 0
+	runs5, runs6 := 0, 0
 
-	for x := 0; x < m.symbolSize; x++ {
-		lastValue := m.get(x, 0)
-		count := 1
-
-		for y := 1; y < m.symbolSize; y++ {
-			v := m.get(x, y)
-
-			if v != lastValue {
-				count = 1
-				lastValue = v
-			} else {
-				count++
-				if count == 6 {
-					penalty += penaltyWeight1 + 1
-				} else if count > 6 {
-					penalty++
-				}
+	// Rows: Each module is compared with the one to its left.
+	columns := m.symbolColumns(m.quietZoneSize + 1)
+
+	for y := m.quietZoneSize; y < m.quietZoneSize+m.symbolSize; y++ {
+		// run[k] has the modules set which end k+1 matches. The carries are
+		// the top bits of the previous word, shifted into the next.
+		var run, carry [6]uint64
+		var moduleCarry uint64
+
+		for i, w := range m.module.row(y) {
+			match := ^(w ^ (w<<1 | moduleCarry)) & columns[i]
+			moduleCarry = w >> 63
+
+			run[0] = match
+			for k := 1; k < len(run); k++ {
+				run[k] = match & (run[k-1]<<1 | carry[k-1])
+			}
+			for k := range carry {
+				carry[k] = run[k] >> 63
 			}
+
+			runs5 += bits.OnesCount64(run[4])
+			runs6 += bits.OnesCount64(run[5])
 		}
 	}
 
-	for y := 0; y < m.symbolSize; y++ {
-		lastValue := m.get(0, y)
-		count := 1
-
-		for x := 1; x < m.symbolSize; x++ {
-			v := m.get(x, y)
-
-			if v != lastValue {
-				count = 1
-				lastValue = v
-			} else {
-				count++
-				if count == 6 {
-					penalty += penaltyWeight1 + 1
-				} else if count > 6 {
-					penalty++
-				}
+	// Columns: Each module is compared with the one above. previous holds run
+	// for each word of the row above.
+	columns = m.symbolColumns(m.quietZoneSize)
+	previous := make([][6]uint64, m.module.stride)
+
+	for y := m.quietZoneSize + 1; y < m.quietZoneSize+m.symbolSize; y++ {
+		above := m.module.row(y - 1)
+		current := m.module.row(y)
+
+		for i := range current {
+			match := ^(above[i] ^ current[i]) & columns[i]
+
+			var run [6]uint64
+			run[0] = match
+			for k := 1; k < len(run); k++ {
+				run[k] = match & previous[i][k-1]
 			}
+			previous[i] = run
+
+			runs5 += bits.OnesCount64(run[4])
+			runs6 += bits.OnesCount64(run[5])
 		}
 	}
 
-	return penalty
+	return 4*runs5 - 3*runs6
 }
 
 // penalty2 returns the penalty score for "block of modules in the same colour".
 //
 // m*n: score = penaltyWeight2 * (m-1) * (n-1).
+//
+// Each 2x2 block of modules in the same colour is found a word at a time, by
+// comparing each row with the row above, and with itself shifted by one
+// module.
 func (m *symbol) penalty2() int {
 	penalty := 0
 
-	for y := 1; y < m.symbolSize; y++ {
-		for x := 1; x < m.symbolSize; x++ {
-			topLeft := m.get(x-1, y-1)
-			above := m.get(x, y-1)
-			left := m.get(x-1, y)
-			current := m.get(x, y)
+	// Modules which are the bottom right of a 2x2 block in the symbol.
+	columns := m.symbolColumns(m.quietZoneSize + 1)
 
-			if current == left && current == above && current == topLeft {
-				penalty++
-			}
-		}
+	for y := m.quietZoneSize + 1; y < m.quietZoneSize+m.symbolSize; y++ {
+		penalty += sameColourBlocks(m.module.row(y-1), m.module.row(y), columns)
 	}
 
 	return penalty * penaltyWeight2
 }
 
+// sameColourBlocks returns the number of 2x2 blocks of modules in the same
+// colour, with their bottom right module in current and in columns. above is
+// the row above current.
+func sameColourBlocks(above []uint64, current []uint64, columns []uint64) int {
+	n := 0
+
+	// Carries of the previous word's top bit, for the shifts.
+	var vertical, horizontal uint64
+
+	for i := range current {
+		// Set if the module is the same colour as the one above.
+		v := ^(above[i] ^ current[i])
+
+		// Set if the module is the same colour as the one to its left.
+		left := current[i]<<1 | horizontal
+		h := ^(current[i] ^ left)
+
+		block := v & (v<<1 | vertical) & h & columns[i]
+		n += bits.OnesCount64(block)
+
+		vertical = v >> 63
+		horizontal = current[i] >> 63
+	}
+
+	return n
+}
+
 // penalty3 returns the penalty score for "1:1:3:1:1 ratio
 // (dark:light:dark:light:dark) pattern in row/column, preceded or followed by
 // light area 4 modules wide".
 //
 // Existence of the pattern scores penaltyWeight3.
+//
+// The columns are scored as the rows of the transposed symbol, so that both
+// are read a word at a time.
 func (m *symbol) penalty3() int {
-	penalty := 0
+	return m.penalty3Rows(m.module) + m.penalty3Rows(m.module.transpose())
+}
 
-	for y := 0; y < m.symbolSize; y++ {
-		var bitBuffer int16 = 0x00
+// penalty3Rows returns the penalty3 score of the rows of b, a matrix the size
+// of bitmap().
+func (m *symbol) penalty3Rows(b bitMatrix) int {
+	penalty := 0
 
-		for x := 0; x < m.symbolSize; x++ {
-			bitBuffer <<= 1
-			if v := m.get(x, y); v {
-				bitBuffer |= 1
-			}
+	start := m.quietZoneSize
+	end := start + m.symbolSize
 
-			switch bitBuffer & 0x7ff {
-			// 0b000 0101 1101 or 0b10111010000
-			// 0x05d           or 0x5d0
-			case 0x05d, 0x5d0:
-				penalty += penaltyWeight3
-				bitBuffer = 0xFF
-			default:
-				if x == m.symbolSize-1 && (bitBuffer&0x7f) == 0x5d {
-					penalty += penaltyWeight3
-					bitBuffer = 0xFF
-				}
-			}
-		}
-	}
+	for y := start; y < end; y++ {
+		row := b.row(y)
 
-	for x := 0; x < m.symbolSize; x++ {
 		var bitBuffer int16 = 0x00
+		w := row[start/64] >> uint(start%64)
 
-		for y := 0; y < m.symbolSize; y++ {
-			bitBuffer <<= 1
-			if v := m.get(x, y); v {
-				bitBuffer |= 1
+		for x := start; x < end; x++ {
+			if x%64 == 0 {
+				w = row[x/64]
 			}
 
+			bitBuffer <<= 1
+			bitBuffer |= int16(w & 1)
+			w >>= 1
+
 			switch bitBuffer & 0x7ff {
 			// 0b000 0101 1101 or 0b10111010000
 			// 0x05d           or 0x5d0
@@ -345,7 +431,7 @@ func (m *symbol) penalty3() int {
 				penalty += penaltyWeight3
 				bitBuffer = 0xFF
 			default:
-				if y == m.symbolSize-1 && (bitBuffer&0x7f) == 0x5d {
+				if x == end-1 && (bitBuffer&0x7f) == 0x5d {
 					penalty += penaltyWeight3
 					bitBuffer = 0xFF
 				}
@@ -356,18 +442,19 @@ func (m *symbol) penalty3() int {
 	return penalty
 }
 
-// penalty4 returns the penalty score...
+// penalty4 returns the penalty score for the proportion of dark modules in
+// the symbol.
+//
+// Each 5% deviation from 50% dark scores penaltyWeight4.
 func (m *symbol) penalty4() int {
-	numModules := m.symbolSize * m.symbolSize
-	numDarkModules := 0
+	// Modules in the quiet zone are never dark.
+	return m.darkModulePenalty(m.module.count())
+}
 
-	for x := 0; x < m.symbolSize; x++ {
-		for y := 0; y < m.symbolSize; y++ {
-			if v := m.get(x, y); v {
-				numDarkModules++
-			}
-		}
-	}
+// darkModulePenalty returns the penalty4 score of a symbol with numDarkModules
+// dark modules.
+func (m *symbol) darkModulePenalty(numDarkModules int) int {
+	numModules := m.symbolSize * m.symbolSize
 
 	numDarkModuleDeviation := numModules/2 - numDarkModules
 	if numDarkModuleDeviation < 0 {
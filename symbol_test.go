@@ -3,7 +3,12 @@
 
 package qrcode
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	bitset "github.com/skip2/go-qrcode/bitset"
+)
 
 func TestSymbolBasic(t *testing.T) {
 	size := 10
@@ -332,3 +337,54 @@ func TestSymbolPenalties(t *testing.T) {
 		}
 	}
 }
+
+// version40Data returns a version 40 QR Code, and its data ready to be added
+// to a symbol.
+func version40Data(b *testing.B) (*QRCode, *bitset.Bitset) {
+	q, err := New(strings.Repeat("0", 7089), Low)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+
+	q.addTerminatorBits(q.version.numTerminatorBitsRequired(q.data.Len()))
+	q.addPadding()
+
+	return q, q.encodeBlocks()
+}
+
+func BenchmarkBuildRegularSymbol(b *testing.B) {
+	q, encoded := version40Data(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for mask := 0; mask < 8; mask++ {
+			if _, err := buildRegularSymbol(q.version, mask, encoded, true); err != nil {
+				b.Fatal(err.Error())
+			}
+		}
+	}
+}
+
+func BenchmarkPenaltyScore(b *testing.B) {
+	q, encoded := version40Data(b)
+
+	var symbols []*symbol
+	for mask := 0; mask < 8; mask++ {
+		s, err := buildRegularSymbol(q.version, mask, encoded, true)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+		symbols = append(symbols, s)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for _, s := range symbols {
+			s.penaltyScore()
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// go-qrcode
+// Copyright 2014 Tom Harwood
+
+package qrcode
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// textOutline is a line of text drawn as a path, in a coordinate system one
+// unit per em, with the origin on the baseline at the start of the text.
+type textOutline struct {
+	path Path
+
+	// Advance width of the text, and the font's ascent and descent (both
+	// positive), in ems.
+	width, ascent, descent float64
+}
+
+// newTextOutline returns text drawn with fnt. A nil fnt selects the embedded
+// 7x13 bitmap font, whose pixels are drawn as squares.
+func newTextOutline(text string, fnt *sfnt.Font) (*textOutline, error) {
+	if fnt == nil {
+		return newBitmapTextOutline(text, basicfont.Face7x13), nil
+	}
+
+	t := &textOutline{}
+
+	// Glyphs are loaded one unit per font unit, then scaled to ems.
+	var b sfnt.Buffer
+	upem := float64(fnt.UnitsPerEm())
+	ppem := fixed.Int26_6(fnt.UnitsPerEm()) << 6
+	ems := func(v fixed.Int26_6) float64 {
+		return float64(v) / 64 / upem
+	}
+
+	metrics, err := fnt.Metrics(&b, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	t.ascent, t.descent = ems(metrics.Ascent), ems(metrics.Descent)
+
+	var prev sfnt.GlyphIndex
+	for i, r := range []rune(text) {
+		g, err := fnt.GlyphIndex(&b, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			// Fonts without kerning return an error.
+			if kern, err := fnt.Kern(&b, prev, g, ppem, font.HintingNone); err == nil {
+				t.width += ems(kern)
+			}
+		}
+
+		segments, err := fnt.LoadGlyph(&b, g, ppem, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		pt := func(p fixed.Point26_6) (float64, float64) {
+			return t.width + ems(p.X), ems(p.Y)
+		}
+
+		for j, s := range segments {
+			switch s.Op {
+			case sfnt.SegmentOpMoveTo:
+				if j > 0 {
+					t.path.Close()
+				}
+				t.path.MoveTo(pt(s.Args[0]))
+			case sfnt.SegmentOpLineTo:
+				t.path.LineTo(pt(s.Args[0]))
+			case sfnt.SegmentOpQuadTo:
+				cx, cy := pt(s.Args[0])
+				x, y := pt(s.Args[1])
+				t.path.QuadTo(cx, cy, x, y)
+			case sfnt.SegmentOpCubeTo:
+				c1x, c1y := pt(s.Args[0])
+				c2x, c2y := pt(s.Args[1])
+				x, y := pt(s.Args[2])
+				t.path.CubeTo(c1x, c1y, c2x, c2y, x, y)
+			}
+		}
+		if len(segments) > 0 {
+			t.path.Close()
+		}
+
+		advance, err := fnt.GlyphAdvance(&b, g, ppem, font.HintingNone)
+		if err != nil {
+			return nil, err
+		}
+		t.width += ems(advance)
+
+		prev = g
+	}
+
+	return t, nil
+}
+
+// newBitmapTextOutline returns text drawn with the bitmap font face, one em
+// per line height.
+func newBitmapTextOutline(text string, face *basicfont.Face) *textOutline {
+	px := 1 / float64(face.Height)
+
+	t := &textOutline{
+		ascent:  float64(face.Ascent) * px,
+		descent: float64(face.Descent) * px,
+	}
+
+	dot := 0
+	for _, r := range text {
+		dr, mask, maskp, advance, ok := face.Glyph(fixed.P(dot, 0), r)
+		if !ok {
+			dot += face.Advance
+			continue
+		}
+
+		// Set pixels, in horizontal runs.
+		for y := 0; y < dr.Dy(); y++ {
+			for x := 0; x < dr.Dx(); x++ {
+				set := func(x int) bool {
+					_, _, _, a := mask.At(maskp.X+x, maskp.Y+y).RGBA()
+					return a >= 0x8000
+				}
+
+				if !set(x) {
+					continue
+				}
+
+				run := 1
+				for x+run < dr.Dx() && set(x+run) {
+					run++
+				}
+
+				t.path.AddRect(float64(dr.Min.X+x)*px, float64(dr.Min.Y+y)*px, float64(run)*px, px)
+				x += run - 1
+			}
+		}
+
+		dot += advance.Round()
+	}
+
+	t.width = float64(dot) * px
+
+	return t
+}
@@ -3013,6 +3013,83 @@ func (v qrCodeVersion) numBlocks() int {
 	return numBlocks
 }
 
+// numECCodewords returns the total number of error correction codewords.
+func (v qrCodeVersion) numECCodewords() int {
+	n := 0
+	for _, b := range v.block {
+		n += b.numBlocks * (b.numCodewords - b.numDataCodewords)
+	}
+
+	return n
+}
+
+// numMisdecodeProtectionCodewords returns the number of error correction
+// codewords in each block reserved to protect against misdecoding, rather
+// than for correcting errors. See ISO/IEC 18004 table 9.
+func (v qrCodeVersion) numMisdecodeProtectionCodewords() int {
+	switch {
+	case v.version == 1 && v.level == Low:
+		return 3
+	case v.version == 1 && v.level == Medium:
+		return 2
+	case v.version == 1:
+		return 1
+	case v.version == 2 && v.level == Low:
+		return 2
+	case v.version == 3 && v.level == Low:
+		return 1
+	}
+
+	return 0
+}
+
+// blockCodeword identifies a codeword by its block, and its index within the
+// block. The data codewords are first, then the error correction codewords.
+type blockCodeword struct {
+	block int
+	index int
+}
+
+// interleavedCodewords returns the codeword at each position of the final
+// (interleaved) codeword sequence built by encodeBlocks: The data codewords of
+// each block in turn, then the error correction codewords.
+func (v qrCodeVersion) interleavedCodewords() []blockCodeword {
+	var numData, numEC []int
+	for _, b := range v.block {
+		for j := 0; j < b.numBlocks; j++ {
+			numData = append(numData, b.numDataCodewords)
+			numEC = append(numEC, b.numCodewords-b.numDataCodewords)
+		}
+	}
+
+	var result []blockCodeword
+
+	for _, isData := range []bool{true, false} {
+		for i := 0; ; i++ {
+			placed := false
+
+			for block := range numData {
+				switch {
+				case isData && i < numData[block]:
+					result = append(result, blockCodeword{block, i})
+				case !isData && i < numEC[block]:
+					result = append(result, blockCodeword{block, numData[block] + i})
+				default:
+					continue
+				}
+
+				placed = true
+			}
+
+			if !placed {
+				break
+			}
+		}
+	}
+
+	return result
+}
+
 // numBitsToPadToCodeword returns the number of bits required to pad data of
 // length numDataBits upto the nearest codeword size.
 func (v qrCodeVersion) numBitsToPadToCodeword(numDataBits int) int {